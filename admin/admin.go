@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/config"
+	"stan.com/stantest/controllers"
+)
+
+// New creates the admin echo instance serving profiling and runtime
+// introspection. It must only ever be bound to a private address.
+// appLogger is the logger of the public server, its level can be toggled here.
+func New(cfg *config.Config, appLogger echo.Logger) *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Logger = appLogger
+
+	// everything here needs the admin credentials
+	e.Use(middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
+		userOK := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Admin.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Admin.Password)) == 1
+		return userOK && passOK, nil
+	}))
+
+	// go tool pprof compatible endpoints
+	debug := e.Group("/debug")
+	{
+		debug.GET("/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+		debug.GET("/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+		debug.GET("/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+		debug.GET("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+		debug.POST("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+		debug.GET("/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+		debug.GET("/pprof/:profile", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+		debug.GET("/vars", echo.WrapHandler(expvar.Handler()))
+	}
+
+	// runtime introspection
+	a := e.Group("/admin")
+	{
+		a.GET("/config", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, cfg)
+		})
+		a.GET("/goroutines", func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+			c.Response().WriteHeader(http.StatusOK)
+			return runtimepprof.Lookup("goroutine").WriteTo(c.Response(), 2)
+		})
+		a.GET("/catalogue", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, controllers.CatalogueSummary(catalogue.Current.Snapshot()))
+		})
+		a.PUT("/catalogue", controllers.UploadCatalogue)
+		a.GET("/log-level", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, map[string]string{"level": config.LogLevelName(appLogger.Level())})
+		})
+		a.PUT("/log-level", func(c echo.Context) error {
+			var body struct {
+				Level string `json:"level"`
+			}
+			if err := c.Bind(&body); err != nil || body.Level == "" {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "level is required"})
+			}
+			level, err := config.ParseLogLevel(body.Level)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			appLogger.SetLevel(level)
+			appLogger.Infof("log level changed to %s", config.LogLevelName(level))
+			return c.JSON(http.StatusOK, map[string]string{"level": config.LogLevelName(level)})
+		})
+	}
+
+	return e
+}
@@ -0,0 +1,216 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/config"
+	"stan.com/stantest/controllers"
+)
+
+func TestAdmin(t *testing.T) {
+	cfg := &config.Config{Admin: config.Admin{Enabled: true, Username: "ops", Password: "s3cret"}}
+	appLogger := log.New("-")
+	appLogger.SetOutput(&bytes.Buffer{})
+	appLogger.SetLevel(log.DEBUG)
+	e := New(cfg, appLogger)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		noAuth         bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Credentials are required",
+			method:         http.MethodGet,
+			path:           "/admin/config",
+			noAuth:         true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Effective config hides the password",
+			method:         http.MethodGet,
+			path:           "/admin/config",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Username":"ops"`,
+		},
+		{
+			name:           "pprof index",
+			method:         http.MethodGet,
+			path:           "/debug/pprof/",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "goroutine",
+		},
+		{
+			name:           "expvar",
+			method:         http.MethodGet,
+			path:           "/debug/vars",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "memstats",
+		},
+		{
+			name:           "Goroutine dump",
+			method:         http.MethodGet,
+			path:           "/admin/goroutines",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "goroutine",
+		},
+		{
+			name:           "Unknown log level",
+			method:         http.MethodPut,
+			path:           "/admin/log-level",
+			body:           `{"level": "loud"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Catalogue without a payload",
+			method:         http.MethodPut,
+			path:           "/admin/catalogue",
+			body:           `{"shows": []}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Catalogue upload needs credentials",
+			method:         http.MethodPut,
+			path:           "/admin/catalogue",
+			body:           `{"payload": []}`,
+			noAuth:         true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if !tt.noAuth {
+				req.SetBasicAuth("ops", "s3cret")
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "s3cret")
+			if tt.expectedBody != "" {
+				assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestAdminToggleLogLevel(t *testing.T) {
+	cfg := &config.Config{Admin: config.Admin{Enabled: true, Username: "ops", Password: "s3cret"}}
+	appLogger := log.New("-")
+	appLogger.SetOutput(&bytes.Buffer{})
+	appLogger.SetLevel(log.DEBUG)
+	e := New(cfg, appLogger)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewBufferString(`{"level": "warn"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.SetBasicAuth("ops", "s3cret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, log.WARN, appLogger.Level())
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	req.SetBasicAuth("ops", "s3cret")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "warn", body["level"])
+}
+
+func TestAdminUploadCatalogue(t *testing.T) {
+	previous := catalogue.Current
+	catalogue.Current = catalogue.NewStore()
+	defer func() { catalogue.Current = previous }()
+
+	cfg := &config.Config{Admin: config.Admin{Enabled: true, Username: "ops", Password: "s3cret"}}
+	appLogger := log.New("-")
+	appLogger.SetOutput(&bytes.Buffer{})
+	e := New(cfg, appLogger)
+
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/admin/catalogue", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.SetBasicAuth("ops", "s3cret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	snapshot := catalogue.Current.Snapshot()
+	assert.Len(t, snapshot.Episodes, 1)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/catalogue", nil)
+	req.SetBasicAuth("ops", "s3cret")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var summary map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, float64(1), summary["shows"])
+	assert.Equal(t, snapshot.Version, summary["version"])
+}
+
+func TestAdminUploadCatalogueRefused(t *testing.T) {
+	previous := catalogue.Current
+	catalogue.Current = catalogue.NewStore()
+	defer func() { catalogue.Current = previous }()
+
+	settings := config.Default()
+	settings.Validation.DuplicatePolicy = config.DUPLICATE_POLICY_REJECT
+	settings.MaxBodyBytes = 512
+	controllers.Configure(settings)
+	defer controllers.Configure(config.Default())
+
+	cfg := &config.Config{Admin: config.Admin{Enabled: true, Username: "ops", Password: "s3cret"}}
+	appLogger := log.New("-")
+	appLogger.SetOutput(&bytes.Buffer{})
+	e := New(cfg, appLogger)
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedCode  int
+		expectedError string
+	}{
+		{"Not JSON", `{`, http.StatusBadRequest, "Could not decode request: JSON parsing failed"},
+		{"No payload", `{}`, http.StatusBadRequest, "Could not decode request: payload is required"},
+		{"Duplicate slugs", `{"payload": [{"slug": "show/a", "title": "A"}, {"slug": "show/a", "title": "B"}]}`,
+			http.StatusBadRequest, "Could not decode catalogue: duplicate slug show/a"},
+		{"Too large", `{"payload": [], "pad": "` + strings.Repeat("a", 512) + `"}`,
+			http.StatusRequestEntityTooLarge, "Could not decode catalogue: body is larger than 512 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/catalogue", bytes.NewBufferString(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.SetBasicAuth("ops", "s3cret")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			var body map[string]string
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedError, body["error"])
+			assert.True(t, catalogue.Current.Snapshot().Empty())
+		})
+	}
+}
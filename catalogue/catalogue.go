@@ -0,0 +1,91 @@
+package catalogue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"stan.com/stantest/models"
+)
+
+// Snapshot is the catalogue as it was at one point, it is never modified
+type Snapshot struct {
+	Episodes []models.Episode
+	// Version changes whenever the content does, it is stable across restarts
+	Version string
+	// UpdatedAt is when the catalogue was stored
+	UpdatedAt time.Time
+}
+
+// Empty reports whether no catalogue has been stored yet
+func (s *Snapshot) Empty() bool {
+	return s.Version == ""
+}
+
+// Store holds the uploaded catalogue of shows. Readers get immutable
+// snapshots, so a replace never disturbs requests in flight.
+type Store struct {
+	mu       sync.RWMutex
+	snapshot *Snapshot
+	now      func() time.Time
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{snapshot: &Snapshot{}, now: time.Now}
+}
+
+// Current is the catalogue served by the API
+var Current = NewStore()
+
+// Replace stores a new catalogue, the episodes must not be modified afterwards
+func (s *Store) Replace(episodes []models.Episode) (*Snapshot, error) {
+	raw, err := json.Marshal(episodes)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+
+	snapshot := &Snapshot{
+		Episodes:  episodes,
+		Version:   hex.EncodeToString(sum[:8]),
+		UpdatedAt: s.now().UTC().Truncate(time.Second),
+	}
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+	return snapshot, nil
+}
+
+// Snapshot returns the current catalogue
+func (s *Store) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+// LoadFile stores the payload of an episode request saved as JSON,
+// check may refuse it before it is stored
+func (s *Store) LoadFile(path string, check func([]models.Episode) error) (*Snapshot, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var request models.EpisodeRequest
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse catalogue %s: %w", path, err)
+	}
+	if request.Payload == nil {
+		return nil, fmt.Errorf("catalogue %s has no payload", path)
+	}
+	if check != nil {
+		if err := check(request.Payload); err != nil {
+			return nil, fmt.Errorf("catalogue %s refused: %w", path, err)
+		}
+	}
+	return s.Replace(request.Payload)
+}
@@ -0,0 +1,59 @@
+package catalogue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+func TestStore(t *testing.T) {
+	clock := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return clock }
+	assert.True(t, store.Snapshot().Empty())
+
+	first, err := store.Replace([]models.Episode{{Slug: "show/a"}})
+	assert.NoError(t, err)
+	assert.False(t, first.Empty())
+	assert.Len(t, first.Version, 16)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), first.UpdatedAt)
+	assert.Same(t, first, store.Snapshot())
+
+	// same content, same version
+	again, err := store.Replace([]models.Episode{{Slug: "show/a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, first.Version, again.Version)
+
+	changed, err := store.Replace([]models.Episode{{Slug: "show/b"}})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Version, changed.Version)
+	// earlier snapshots are untouched
+	assert.Equal(t, "show/a", first.Episodes[0].Slug)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "catalogue.json")
+	assert.NoError(t, os.WriteFile(valid, []byte(`{"payload": [{"slug": "show/a"}, {"slug": "show/b"}]}`), 0o644))
+	empty := filepath.Join(dir, "empty.json")
+	assert.NoError(t, os.WriteFile(empty, []byte(`{}`), 0o644))
+
+	store := NewStore()
+	snapshot, err := store.LoadFile(valid, nil)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Episodes, 2)
+
+	_, err = store.LoadFile(empty, nil)
+	assert.EqualError(t, err, "catalogue "+empty+" has no payload")
+	_, err = store.LoadFile(filepath.Join(dir, "missing.json"), nil)
+	assert.Error(t, err)
+	_, err = store.LoadFile(valid, func([]models.Episode) error { return errors.New("no thanks") })
+	assert.EqualError(t, err, "catalogue "+valid+" refused: no thanks")
+	// failed loads keep the stored catalogue
+	assert.Same(t, snapshot, store.Snapshot())
+}
@@ -0,0 +1,115 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RGB is an opaque colour, alpha is dropped when normalising
+type RGB struct {
+	R, G, B uint8
+}
+
+var (
+	hexPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6}|[0-9a-f]{8})$`)
+	rgbPattern = regexp.MustCompile(`^rgb\(\s*([0-9.]+%?)\s*,\s*([0-9.]+%?)\s*,\s*([0-9.]+%?)\s*\)$`)
+)
+
+// Parse accepts #rgb, #rrggbb, #rrggbbaa, rgb(r, g, b) and CSS named colours
+func Parse(s string) (RGB, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	if named, ok := namedColors[value]; ok {
+		value = named
+	}
+
+	if hexPattern.MatchString(value) {
+		digits := value[1:]
+		if len(digits) == 3 {
+			digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+		}
+		n, _ := strconv.ParseUint(digits[:6], 16, 32)
+		return RGB{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n)}, nil
+	}
+
+	if m := rgbPattern.FindStringSubmatch(value); m != nil {
+		var channels [3]uint8
+		for i, raw := range m[1:] {
+			channel, err := parseChannel(raw)
+			if err != nil {
+				return RGB{}, fmt.Errorf("invalid colour %q: %w", s, err)
+			}
+			channels[i] = channel
+		}
+		return RGB{R: channels[0], G: channels[1], B: channels[2]}, nil
+	}
+
+	return RGB{}, fmt.Errorf("invalid colour %q", s)
+}
+
+// parseChannel reads 0-255 or 0%-100%
+func parseChannel(raw string) (uint8, error) {
+	if pct, ok := strings.CutSuffix(raw, "%"); ok {
+		v, err := strconv.ParseFloat(pct, 64)
+		if err != nil || v < 0 || v > 100 {
+			return 0, fmt.Errorf("channel %s out of range", raw)
+		}
+		return uint8(math.Round(v * 255 / 100)), nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 || v > 255 {
+		return 0, fmt.Errorf("channel %s out of range", raw)
+	}
+	return uint8(v), nil
+}
+
+// Normalize canonicalises any accepted colour to lowercase #rrggbb
+func Normalize(s string) (string, error) {
+	c, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return c.Hex(), nil
+}
+
+// Hex formats the colour as lowercase #rrggbb
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Luminance is the WCAG 2 relative luminance
+func (c RGB) Luminance() float64 {
+	linear := func(channel uint8) float64 {
+		v := float64(channel) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// ContrastRatio is the WCAG 2 contrast ratio between two colours, 1 to 21
+func ContrastRatio(a, b RGB) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+var (
+	black = RGB{}
+	white = RGB{R: 255, G: 255, B: 255}
+)
+
+// ContrastText picks black or white text, whichever reads better on background.
+// One of them always meets WCAG AA for normal text (4.5:1).
+func ContrastText(background RGB) RGB {
+	if ContrastRatio(background, black) >= ContrastRatio(background, white) {
+		return black
+	}
+	return white
+}
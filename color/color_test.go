@@ -0,0 +1,66 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Canonical hex", input: "#ff7800", expected: "#ff7800"},
+		{name: "Upper case hex", input: "#DF0000", expected: "#df0000"},
+		{name: "Short hex", input: "#0af", expected: "#00aaff"},
+		{name: "Hex with alpha", input: "#0084da80", expected: "#0084da"},
+		{name: "rgb()", input: "rgb(255, 120, 0)", expected: "#ff7800"},
+		{name: "rgb() percentages", input: "rgb(100%, 0%, 50%)", expected: "#ff0080"},
+		{name: "Named colour", input: "RebeccaPurple", expected: "#663399"},
+		{name: "Surrounding spaces", input: "  #FFF ", expected: "#ffffff"},
+		{name: "Missing hash", input: "ff7800", wantErr: true},
+		{name: "Bad length", input: "#ff78", wantErr: true},
+		{name: "Channel out of range", input: "rgb(256, 0, 0)", wantErr: true},
+		{name: "Unknown name", input: "stanblue", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := Normalize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestContrastText(t *testing.T) {
+	tests := []struct {
+		background string
+		expected   string
+	}{
+		{background: "#ffffff", expected: "#000000"},
+		{background: "#000000", expected: "#ffffff"},
+		{background: "#ff7800", expected: "#000000"},
+		{background: "#0084da", expected: "#000000"},
+		{background: "#df0000", expected: "#ffffff"},
+		{background: "navy", expected: "#ffffff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.background, func(t *testing.T) {
+			background, err := Parse(tt.background)
+			assert.NoError(t, err)
+			text := ContrastText(background)
+			assert.Equal(t, tt.expected, text.Hex())
+			// WCAG AA for normal text
+			assert.GreaterOrEqual(t, ContrastRatio(background, text), 4.5)
+		})
+	}
+}
@@ -1,9 +1,652 @@
 package config
 
-import "github.com/labstack/gommon/log"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
 
 const (
 	// DEFAULT_PORT is the default port for the API server
 	DEFAULT_PORT    = "80"
 	LOG_LEVEL_DEBUG = log.DEBUG
+
+	// LOG_OUTPUT_STDOUT writes logs to the console, anything else is a file path
+	LOG_OUTPUT_STDOUT = "stdout"
+	// defaults for rotated log files
+	DEFAULT_LOG_MAX_SIZE_MB     = 100
+	DEFAULT_LOG_ROTATE_INTERVAL = 24 * time.Hour
+	DEFAULT_LOG_MAX_BACKUPS     = 7
+	DEFAULT_LOG_MAX_AGE         = 30 * 24 * time.Hour
+
+	// defaults for HTTPS serving
+	DEFAULT_TLS_MIN_VERSION     = "1.2"
+	DEFAULT_TLS_RELOAD_INTERVAL = 30 * time.Second
+
+	// server timeouts, 0 disables a timeout
+	DEFAULT_READ_HEADER_TIMEOUT = 5 * time.Second
+	DEFAULT_READ_TIMEOUT        = 30 * time.Second
+	DEFAULT_WRITE_TIMEOUT       = 60 * time.Second
+	DEFAULT_IDLE_TIMEOUT        = 120 * time.Second
+	DEFAULT_REQUEST_TIMEOUT     = 30 * time.Second
+	DEFAULT_SHUTDOWN_GRACE      = 10 * time.Second
+
+	// DEFAULT_FEED_TIMEZONE applies to feed dates sent without an offset
+	DEFAULT_FEED_TIMEZONE = "UTC"
+
+	// HTML_POLICY_* decide what happens to unsafe markup in episode fields
+	HTML_POLICY_SANITIZE = "sanitize"
+	HTML_POLICY_REJECT   = "reject"
+	HTML_POLICY_TEXT     = "text"
+
+	// COLOUR_POLICY_* decide what happens to an invalid primary colour
+	COLOUR_POLICY_REJECT = "reject"
+	COLOUR_POLICY_DROP   = "drop"
+	COLOUR_POLICY_KEEP   = "keep"
+
+	// DEFAULT_SLUG_PREFIX is required at the start of every show slug
+	DEFAULT_SLUG_PREFIX = "show/"
+
+	// DUPLICATE_POLICY_* decide what happens to episodes sharing a slug
+	DUPLICATE_POLICY_KEEP_FIRST = "keep-first"
+	DUPLICATE_POLICY_KEEP_LAST  = "keep-last"
+	DUPLICATE_POLICY_MERGE      = "merge"
+	DUPLICATE_POLICY_REJECT     = "reject"
+
+	// DEFAULT_URL_MAX_LENGTH caps feed URLs, matching what CDNs reliably accept
+	DEFAULT_URL_MAX_LENGTH = 2048
+
+	// IMAGE_CHECK_* decide whether show images are fetched and what happens
+	// to episodes whose image is unusable
+	IMAGE_CHECK_OFF  = "off"
+	IMAGE_CHECK_MARK = "mark"
+	IMAGE_CHECK_DROP = "drop"
+	// defaults for image checks
+	DEFAULT_IMAGE_CHECK_CONCURRENCY = 8
+	DEFAULT_IMAGE_CHECK_TIMEOUT     = 5 * time.Second
+	DEFAULT_IMAGE_CHECK_CACHE_TTL   = 10 * time.Minute
+
+	// DEFAULT_CATALOGUE_MAX_AGE is how long clients may cache catalogue queries
+	DEFAULT_CATALOGUE_MAX_AGE = 60 * time.Second
+
+	// defaults for fuzzy title matching and suggestions
+	DEFAULT_FUZZY_MAX_DISTANCE   = 2
+	DEFAULT_FUZZY_MIN_SIMILARITY = 0.6
+	DEFAULT_SUGGEST_LIMIT        = 5
+
+	// defaults for the response cache, off unless enabled
+	DEFAULT_RESPONSE_CACHE_MAX_ENTRIES = 1000
+	DEFAULT_RESPONSE_CACHE_MAX_BYTES   = 64 << 20
+	DEFAULT_RESPONSE_CACHE_TTL         = 5 * time.Minute
+
+	// DEFAULT_MAX_INFLATED_BYTES caps compressed request bodies once inflated
+	DEFAULT_MAX_INFLATED_BYTES = 64 << 20
+	// DEFAULT_PARALLEL_MIN_EPISODES is the fewest matching episodes processed by a
+	// pool of workers, fewer don't repay starting it
+	DEFAULT_PARALLEL_MIN_EPISODES = 1000
+
+	// DEFAULT_COMPRESSION_MIN_SIZE is the smallest response worth compressing
+	DEFAULT_COMPRESSION_MIN_SIZE = 1024
+
+	// DEFAULT_ADMIN_ADDR keeps the admin listener on loopback only
+	DEFAULT_ADMIN_ADDR = "127.0.0.1:6060"
 )
+
+// Config holds the effective server configuration
+type Config struct {
+	Port      string
+	LogLevel  log.Lvl
+	AppLog    LogSink
+	AccessLog LogSink
+	TLS       TLS
+	Timeouts  Timeouts
+	Admin     Admin
+	// FeedTimezone is an IANA zone name, e.g. Australia/Sydney
+	FeedTimezone string
+	Validation   Validation
+	API          API
+	Catalogue    Catalogue
+	Search       Search
+	Cache        Cache
+	Compression  Compression
+	Processing   Processing
+	// MaxBodyBytes caps every request body, 0 leaves plain bodies
+	// unlimited as they always were
+	MaxBodyBytes int64
+	// MaxInflatedBytes caps gzip and zstd request bodies once inflated, so
+	// a small upload can't expand without bound. MaxBodyBytes applies to
+	// them too when it is lower.
+	MaxInflatedBytes int64
+
+	// loadErrors are the variables Load couldn't parse
+	loadErrors []error
+}
+
+// Processing tunes how request payloads are filtered
+type Processing struct {
+	// Workers validate and clean up the episodes of a large payload which
+	// matched in parallel, 0 starts one per CPU and 1 processes every
+	// payload sequentially
+	Workers int
+	// ParallelMinEpisodes is the fewest matching episodes handed to the workers
+	ParallelMinEpisodes int
+}
+
+// Compression configures compressing responses, negotiated via Accept-Encoding
+type Compression struct {
+	Enabled bool
+	// MinSize is the smallest response body compressed, smaller ones are
+	// sent as they are
+	MinSize int
+}
+
+// Cache configures conditional requests and the server side response cache
+type Cache struct {
+	// Enabled keeps responses in memory, keyed by request body and options
+	Enabled bool
+	// MaxEntries and MaxBytes bound the cache, 0 means no bound
+	MaxEntries int
+	MaxBytes   int64
+	// TTL is how long a response is served from the cache, responses
+	// depending on the current time can be this stale
+	TTL time.Duration
+	// MaxAge is sent as Cache-Control max-age, 0 sends no-cache so clients
+	// revalidate with the ETag every time
+	MaxAge time.Duration
+}
+
+// Search tunes fuzzy title matching and suggestions
+type Search struct {
+	// FuzzyMaxDistance is the most typos a title match may have
+	FuzzyMaxDistance int
+	// FuzzyMinSimilarity is the lowest match score, 1 less the typos per
+	// character searched for
+	FuzzyMinSimilarity float64
+	// SuggestLimit caps the suggestions returned when a request sets none
+	SuggestLimit int
+}
+
+// Catalogue configures the stored catalogue GET queries run on
+type Catalogue struct {
+	// File is loaded at startup, uploads through the admin server replace it
+	File string
+	// MaxAge is sent as Cache-Control max-age on query responses
+	MaxAge time.Duration
+}
+
+// API controls the lifecycle of the public API versions
+type API struct {
+	// V1Deprecation marks v1 deprecated from this time on, zero leaves it be
+	V1Deprecation time.Time
+	// V1Sunset is when v1 goes away, zero announces no date
+	V1Sunset time.Time
+	// V1DeprecationLink points clients at the migration notes
+	V1DeprecationLink string
+}
+
+// Validation tunes how episode data is checked and cleaned up
+type Validation struct {
+	// HTMLPolicy is sanitize, reject or text
+	HTMLPolicy string
+	// ColourPolicy is reject, drop (clear the colour) or keep (pass it through)
+	ColourPolicy string
+	// GenreTaxonomyFile replaces the built in genre taxonomy when set
+	GenreTaxonomyFile string
+	// SlugPrefix is required at the start of show slugs, empty disables the check
+	SlugPrefix string
+	// DuplicatePolicy is keep-first, keep-last, merge or reject (the whole request)
+	DuplicatePolicy string
+	// URL restricts the links and image URLs episodes may carry
+	URL URLPolicy
+	// ImageCheck fetches show images to verify them, off by default
+	ImageCheck ImageCheck
+	// Schema checks request bodies against the published JSON Schema before decoding
+	Schema bool
+	// RulesFile declares extra validation rules in YAML or JSON profiles
+	RulesFile string
+	// RulesProfile is the profile applied when a request doesn't pick one,
+	// empty applies no declared rules
+	RulesProfile string
+}
+
+// ImageCheck configures fetching show images to verify them
+type ImageCheck struct {
+	// Mode is off, mark (warn in the report) or drop (reject the episode)
+	Mode string
+	// Concurrency is the number of images fetched at once
+	Concurrency int
+	// Timeout bounds each fetch
+	Timeout time.Duration
+	// CacheTTL is how long a result is reused, 0 disables the cache
+	CacheTTL time.Duration
+	// ContentTypes are the accepted media types, empty accepts any image/*
+	ContentTypes []string
+	// MinWidth and MinHeight are the smallest acceptable dimensions, 0 disables
+	MinWidth  int
+	MinHeight int
+}
+
+// URLPolicy restricts feed URLs, credentials in URLs are always refused
+type URLPolicy struct {
+	// AllowHosts limits URLs to these hosts, "*.example.com" matches subdomains
+	AllowHosts []string
+	// DenyHosts are refused, same matching as AllowHosts
+	DenyHosts []string
+	// HTTPSOnly refuses plain http
+	HTTPSOnly bool
+	// UpgradeHTTP rewrites http to https instead of refusing it
+	UpgradeHTTP bool
+	// RejectIPLiterals refuses hosts given as an IP address
+	RejectIPLiterals bool
+	// RejectPrivate refuses loopback, private and link local hosts
+	RejectPrivate bool
+	// MaxLength caps the URL length, 0 disables
+	MaxLength int
+	// ImageExtensions are required on image URLs, e.g. ".jpg", empty allows any
+	ImageExtensions []string
+}
+
+// Admin configures the private debug listener, disabled by default
+type Admin struct {
+	Enabled  bool
+	Addr     string
+	Username string
+	Password string `json:"-"`
+}
+
+// Timeouts bound how long connections and requests may take
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	// Request is the processing deadline handlers see through the request context
+	Request time.Duration
+	// ShutdownGrace is how long in-flight requests get to drain on shutdown
+	ShutdownGrace time.Duration
+}
+
+// TLS configures HTTPS serving, it is enabled when both cert and key are set
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile turns on mutual TLS, clients must present a cert signed by it
+	ClientCAFile string
+	// MinVersion is "1.0" to "1.3"
+	MinVersion string
+	// CipherSuites are IANA names, empty means the Go defaults
+	CipherSuites []string
+	// ReloadInterval is how often cert files are checked for changes
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether HTTPS should be served
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// LogSink describes where one log stream goes and how its files are rotated
+type LogSink struct {
+	// Output is "stdout" or a file path
+	Output string
+	// MaxSizeMB rotates the file once it grows past this size, 0 disables
+	MaxSizeMB int
+	// RotateInterval rotates the file once it has been written for this long, 0 disables
+	RotateInterval time.Duration
+	// MaxBackups is the number of rotated files to keep, 0 keeps all
+	MaxBackups int
+	// MaxAge removes rotated files older than this, 0 keeps them forever
+	MaxAge time.Duration
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	rotation := LogSink{
+		MaxSizeMB:      DEFAULT_LOG_MAX_SIZE_MB,
+		RotateInterval: DEFAULT_LOG_ROTATE_INTERVAL,
+		MaxBackups:     DEFAULT_LOG_MAX_BACKUPS,
+		MaxAge:         DEFAULT_LOG_MAX_AGE,
+		Compress:       true,
+	}
+	appLog := rotation
+	appLog.Output = LOG_OUTPUT_STDOUT
+	accessLog := rotation
+	accessLog.Output = LOG_OUTPUT_STDOUT
+
+	return &Config{
+		Port:      DEFAULT_PORT,
+		LogLevel:  LOG_LEVEL_DEBUG,
+		AppLog:    appLog,
+		AccessLog: accessLog,
+		TLS: TLS{
+			MinVersion:     DEFAULT_TLS_MIN_VERSION,
+			ReloadInterval: DEFAULT_TLS_RELOAD_INTERVAL,
+		},
+		Timeouts: Timeouts{
+			ReadHeader:    DEFAULT_READ_HEADER_TIMEOUT,
+			Read:          DEFAULT_READ_TIMEOUT,
+			Write:         DEFAULT_WRITE_TIMEOUT,
+			Idle:          DEFAULT_IDLE_TIMEOUT,
+			Request:       DEFAULT_REQUEST_TIMEOUT,
+			ShutdownGrace: DEFAULT_SHUTDOWN_GRACE,
+		},
+		Admin: Admin{
+			Addr: DEFAULT_ADMIN_ADDR,
+		},
+		FeedTimezone: DEFAULT_FEED_TIMEZONE,
+		Catalogue: Catalogue{
+			MaxAge: DEFAULT_CATALOGUE_MAX_AGE,
+		},
+		Cache: Cache{
+			MaxEntries: DEFAULT_RESPONSE_CACHE_MAX_ENTRIES,
+			MaxBytes:   DEFAULT_RESPONSE_CACHE_MAX_BYTES,
+			TTL:        DEFAULT_RESPONSE_CACHE_TTL,
+		},
+		Compression: Compression{
+			Enabled: true,
+			MinSize: DEFAULT_COMPRESSION_MIN_SIZE,
+		},
+		MaxInflatedBytes: DEFAULT_MAX_INFLATED_BYTES,
+		Processing: Processing{
+			ParallelMinEpisodes: DEFAULT_PARALLEL_MIN_EPISODES,
+		},
+		Search: Search{
+			FuzzyMaxDistance:   DEFAULT_FUZZY_MAX_DISTANCE,
+			FuzzyMinSimilarity: DEFAULT_FUZZY_MIN_SIMILARITY,
+			SuggestLimit:       DEFAULT_SUGGEST_LIMIT,
+		},
+		Validation: Validation{
+			HTMLPolicy:      HTML_POLICY_SANITIZE,
+			ColourPolicy:    COLOUR_POLICY_DROP,
+			SlugPrefix:      DEFAULT_SLUG_PREFIX,
+			DuplicatePolicy: DUPLICATE_POLICY_KEEP_FIRST,
+			URL: URLPolicy{
+				MaxLength: DEFAULT_URL_MAX_LENGTH,
+			},
+			ImageCheck: ImageCheck{
+				Mode:        IMAGE_CHECK_OFF,
+				Concurrency: DEFAULT_IMAGE_CHECK_CONCURRENCY,
+				Timeout:     DEFAULT_IMAGE_CHECK_TIMEOUT,
+				CacheTTL:    DEFAULT_IMAGE_CHECK_CACHE_TTL,
+			},
+		},
+	}
+}
+
+// Load builds the configuration from STAN_EPISODE_SERVER_* environment
+// variables, falling back to defaults for anything not set. Values which
+// don't parse are reported by Validate.
+func Load() *Config {
+	cfg := Default()
+	env := &envReader{}
+
+	cfg.Port = getEnv("STAN_EPISODE_SERVER_PORT", cfg.Port)
+	if logLevel, err := ParseLogLevel(os.Getenv("STAN_EPISODE_SERVER_LOG_LEVEL")); err == nil {
+		cfg.LogLevel = logLevel
+	} else {
+		env.errs = append(env.errs, fmt.Errorf("STAN_EPISODE_SERVER_LOG_LEVEL: %w", err))
+	}
+
+	for _, sink := range []*LogSink{&cfg.AppLog, &cfg.AccessLog} {
+		sink.MaxSizeMB = env.getEnvInt("STAN_EPISODE_SERVER_LOG_MAX_SIZE_MB", sink.MaxSizeMB)
+		sink.RotateInterval = env.getEnvDuration("STAN_EPISODE_SERVER_LOG_ROTATE_INTERVAL", sink.RotateInterval)
+		sink.MaxBackups = env.getEnvInt("STAN_EPISODE_SERVER_LOG_MAX_BACKUPS", sink.MaxBackups)
+		sink.MaxAge = env.getEnvDuration("STAN_EPISODE_SERVER_LOG_MAX_AGE", sink.MaxAge)
+		sink.Compress = env.getEnvBool("STAN_EPISODE_SERVER_LOG_COMPRESS", sink.Compress)
+	}
+	cfg.AppLog.Output = getEnv("STAN_EPISODE_SERVER_LOG_OUTPUT", cfg.AppLog.Output)
+	cfg.AccessLog.Output = getEnv("STAN_EPISODE_SERVER_ACCESS_LOG_OUTPUT", cfg.AppLog.Output)
+
+	cfg.TLS.CertFile = os.Getenv("STAN_EPISODE_SERVER_TLS_CERT_FILE")
+	cfg.TLS.KeyFile = os.Getenv("STAN_EPISODE_SERVER_TLS_KEY_FILE")
+	cfg.TLS.ClientCAFile = os.Getenv("STAN_EPISODE_SERVER_TLS_CLIENT_CA_FILE")
+	cfg.TLS.MinVersion = getEnv("STAN_EPISODE_SERVER_TLS_MIN_VERSION", cfg.TLS.MinVersion)
+	cfg.TLS.CipherSuites = getEnvList("STAN_EPISODE_SERVER_TLS_CIPHER_SUITES")
+	cfg.TLS.ReloadInterval = env.getEnvDuration("STAN_EPISODE_SERVER_TLS_RELOAD_INTERVAL", cfg.TLS.ReloadInterval)
+
+	cfg.Timeouts.ReadHeader = env.getEnvDuration("STAN_EPISODE_SERVER_READ_HEADER_TIMEOUT", cfg.Timeouts.ReadHeader)
+	cfg.Timeouts.Read = env.getEnvDuration("STAN_EPISODE_SERVER_READ_TIMEOUT", cfg.Timeouts.Read)
+	cfg.Timeouts.Write = env.getEnvDuration("STAN_EPISODE_SERVER_WRITE_TIMEOUT", cfg.Timeouts.Write)
+	cfg.Timeouts.Idle = env.getEnvDuration("STAN_EPISODE_SERVER_IDLE_TIMEOUT", cfg.Timeouts.Idle)
+	cfg.Timeouts.Request = env.getEnvDuration("STAN_EPISODE_SERVER_REQUEST_TIMEOUT", cfg.Timeouts.Request)
+	cfg.Timeouts.ShutdownGrace = env.getEnvDuration("STAN_EPISODE_SERVER_SHUTDOWN_GRACE", cfg.Timeouts.ShutdownGrace)
+
+	cfg.Admin.Enabled = env.getEnvBool("STAN_EPISODE_SERVER_ADMIN_ENABLED", cfg.Admin.Enabled)
+	cfg.Admin.Addr = getEnv("STAN_EPISODE_SERVER_ADMIN_ADDR", cfg.Admin.Addr)
+	cfg.Admin.Username = os.Getenv("STAN_EPISODE_SERVER_ADMIN_USERNAME")
+	cfg.Admin.Password = os.Getenv("STAN_EPISODE_SERVER_ADMIN_PASSWORD")
+
+	cfg.FeedTimezone = getEnv("STAN_EPISODE_SERVER_FEED_TIMEZONE", cfg.FeedTimezone)
+
+	cfg.Validation.HTMLPolicy = strings.ToLower(getEnv("STAN_EPISODE_SERVER_HTML_POLICY", cfg.Validation.HTMLPolicy))
+	cfg.Validation.ColourPolicy = strings.ToLower(getEnv("STAN_EPISODE_SERVER_COLOUR_POLICY", cfg.Validation.ColourPolicy))
+	cfg.Validation.GenreTaxonomyFile = os.Getenv("STAN_EPISODE_SERVER_GENRE_TAXONOMY_FILE")
+	// an explicitly empty prefix turns the prefix check off
+	if prefix, ok := os.LookupEnv("STAN_EPISODE_SERVER_SLUG_PREFIX"); ok {
+		cfg.Validation.SlugPrefix = prefix
+	}
+	cfg.Validation.DuplicatePolicy = strings.ToLower(getEnv("STAN_EPISODE_SERVER_DUPLICATE_POLICY", cfg.Validation.DuplicatePolicy))
+
+	cfg.Validation.URL.AllowHosts = getEnvList("STAN_EPISODE_SERVER_URL_ALLOW_HOSTS")
+	cfg.Validation.URL.DenyHosts = getEnvList("STAN_EPISODE_SERVER_URL_DENY_HOSTS")
+	cfg.Validation.URL.HTTPSOnly = env.getEnvBool("STAN_EPISODE_SERVER_URL_HTTPS_ONLY", cfg.Validation.URL.HTTPSOnly)
+	cfg.Validation.URL.UpgradeHTTP = env.getEnvBool("STAN_EPISODE_SERVER_URL_UPGRADE_HTTP", cfg.Validation.URL.UpgradeHTTP)
+	cfg.Validation.URL.RejectIPLiterals = env.getEnvBool("STAN_EPISODE_SERVER_URL_REJECT_IP_LITERALS", cfg.Validation.URL.RejectIPLiterals)
+	cfg.Validation.URL.RejectPrivate = env.getEnvBool("STAN_EPISODE_SERVER_URL_REJECT_PRIVATE", cfg.Validation.URL.RejectPrivate)
+	cfg.Validation.URL.MaxLength = env.getEnvInt("STAN_EPISODE_SERVER_URL_MAX_LENGTH", cfg.Validation.URL.MaxLength)
+	cfg.Validation.URL.ImageExtensions = getEnvList("STAN_EPISODE_SERVER_IMAGE_EXTENSIONS")
+
+	cfg.Validation.Schema = env.getEnvBool("STAN_EPISODE_SERVER_SCHEMA_VALIDATION", cfg.Validation.Schema)
+	cfg.Validation.RulesFile = os.Getenv("STAN_EPISODE_SERVER_RULES_FILE")
+	cfg.Validation.RulesProfile = os.Getenv("STAN_EPISODE_SERVER_RULES_PROFILE")
+
+	cfg.Validation.ImageCheck.Mode = strings.ToLower(getEnv("STAN_EPISODE_SERVER_IMAGE_CHECK_MODE", cfg.Validation.ImageCheck.Mode))
+	cfg.Validation.ImageCheck.Concurrency = env.getEnvInt("STAN_EPISODE_SERVER_IMAGE_CHECK_CONCURRENCY", cfg.Validation.ImageCheck.Concurrency)
+	cfg.Validation.ImageCheck.Timeout = env.getEnvDuration("STAN_EPISODE_SERVER_IMAGE_CHECK_TIMEOUT", cfg.Validation.ImageCheck.Timeout)
+	cfg.Validation.ImageCheck.CacheTTL = env.getEnvDuration("STAN_EPISODE_SERVER_IMAGE_CHECK_CACHE_TTL", cfg.Validation.ImageCheck.CacheTTL)
+	cfg.Validation.ImageCheck.ContentTypes = getEnvList("STAN_EPISODE_SERVER_IMAGE_CHECK_CONTENT_TYPES")
+	cfg.Validation.ImageCheck.MinWidth = env.getEnvInt("STAN_EPISODE_SERVER_IMAGE_CHECK_MIN_WIDTH", cfg.Validation.ImageCheck.MinWidth)
+	cfg.Validation.ImageCheck.MinHeight = env.getEnvInt("STAN_EPISODE_SERVER_IMAGE_CHECK_MIN_HEIGHT", cfg.Validation.ImageCheck.MinHeight)
+
+	cfg.Catalogue.File = os.Getenv("STAN_EPISODE_SERVER_CATALOGUE_FILE")
+	cfg.Catalogue.MaxAge = env.getEnvDuration("STAN_EPISODE_SERVER_CATALOGUE_MAX_AGE", cfg.Catalogue.MaxAge)
+
+	cfg.Search.FuzzyMaxDistance = env.getEnvInt("STAN_EPISODE_SERVER_FUZZY_MAX_DISTANCE", cfg.Search.FuzzyMaxDistance)
+	cfg.Search.FuzzyMinSimilarity = env.getEnvFloat("STAN_EPISODE_SERVER_FUZZY_MIN_SIMILARITY", cfg.Search.FuzzyMinSimilarity)
+	cfg.Search.SuggestLimit = env.getEnvInt("STAN_EPISODE_SERVER_SUGGEST_LIMIT", cfg.Search.SuggestLimit)
+
+	cfg.Cache.Enabled = env.getEnvBool("STAN_EPISODE_SERVER_RESPONSE_CACHE", cfg.Cache.Enabled)
+	cfg.Cache.MaxEntries = env.getEnvInt("STAN_EPISODE_SERVER_RESPONSE_CACHE_MAX_ENTRIES", cfg.Cache.MaxEntries)
+	cfg.Cache.MaxBytes = int64(env.getEnvInt("STAN_EPISODE_SERVER_RESPONSE_CACHE_MAX_BYTES", int(cfg.Cache.MaxBytes)))
+	cfg.Cache.TTL = env.getEnvDuration("STAN_EPISODE_SERVER_RESPONSE_CACHE_TTL", cfg.Cache.TTL)
+	cfg.Cache.MaxAge = env.getEnvDuration("STAN_EPISODE_SERVER_RESPONSE_MAX_AGE", cfg.Cache.MaxAge)
+
+	cfg.Compression.Enabled = env.getEnvBool("STAN_EPISODE_SERVER_COMPRESSION", cfg.Compression.Enabled)
+	cfg.Compression.MinSize = env.getEnvInt("STAN_EPISODE_SERVER_COMPRESSION_MIN_SIZE", cfg.Compression.MinSize)
+	cfg.MaxBodyBytes = int64(env.getEnvInt("STAN_EPISODE_SERVER_MAX_BODY_BYTES", int(cfg.MaxBodyBytes)))
+	cfg.MaxInflatedBytes = int64(env.getEnvInt("STAN_EPISODE_SERVER_MAX_INFLATED_BYTES", int(cfg.MaxInflatedBytes)))
+
+	cfg.Processing.Workers = env.getEnvInt("STAN_EPISODE_SERVER_WORKERS", cfg.Processing.Workers)
+	cfg.Processing.ParallelMinEpisodes = env.getEnvInt("STAN_EPISODE_SERVER_PARALLEL_MIN_EPISODES", cfg.Processing.ParallelMinEpisodes)
+
+	cfg.API.V1Deprecation = env.getEnvTime("STAN_EPISODE_SERVER_V1_DEPRECATION", cfg.API.V1Deprecation)
+	cfg.API.V1Sunset = env.getEnvTime("STAN_EPISODE_SERVER_V1_SUNSET", cfg.API.V1Sunset)
+	cfg.API.V1DeprecationLink = os.Getenv("STAN_EPISODE_SERVER_V1_DEPRECATION_LINK")
+
+	cfg.loadErrors = env.errs
+	return cfg
+}
+
+// Validate reports settings which can't work, the server refuses to start on them
+func (c *Config) Validate() error {
+	if err := errors.Join(c.loadErrors...); err != nil {
+		return err
+	}
+	switch c.Validation.HTMLPolicy {
+	case HTML_POLICY_SANITIZE, HTML_POLICY_REJECT, HTML_POLICY_TEXT:
+	default:
+		return fmt.Errorf("html policy must be one of %s, %s, %s", HTML_POLICY_SANITIZE, HTML_POLICY_REJECT, HTML_POLICY_TEXT)
+	}
+	switch c.Validation.ColourPolicy {
+	case COLOUR_POLICY_REJECT, COLOUR_POLICY_DROP, COLOUR_POLICY_KEEP:
+	default:
+		return fmt.Errorf("colour policy must be one of %s, %s, %s", COLOUR_POLICY_REJECT, COLOUR_POLICY_DROP, COLOUR_POLICY_KEEP)
+	}
+	switch c.Validation.DuplicatePolicy {
+	case DUPLICATE_POLICY_KEEP_FIRST, DUPLICATE_POLICY_KEEP_LAST, DUPLICATE_POLICY_MERGE, DUPLICATE_POLICY_REJECT:
+	default:
+		return fmt.Errorf("duplicate policy must be one of %s, %s, %s, %s",
+			DUPLICATE_POLICY_KEEP_FIRST, DUPLICATE_POLICY_KEEP_LAST, DUPLICATE_POLICY_MERGE, DUPLICATE_POLICY_REJECT)
+	}
+	if c.Validation.URL.MaxLength < 0 {
+		return fmt.Errorf("url max length must not be negative")
+	}
+	for _, ext := range c.Validation.URL.ImageExtensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("image extension %q must start with a dot", ext)
+		}
+	}
+	switch c.Validation.ImageCheck.Mode {
+	case IMAGE_CHECK_OFF, IMAGE_CHECK_MARK, IMAGE_CHECK_DROP:
+	default:
+		return fmt.Errorf("image check mode must be one of %s, %s, %s", IMAGE_CHECK_OFF, IMAGE_CHECK_MARK, IMAGE_CHECK_DROP)
+	}
+	if c.Validation.ImageCheck.Mode != IMAGE_CHECK_OFF && c.Validation.ImageCheck.Concurrency < 1 {
+		return fmt.Errorf("image check concurrency must be at least 1")
+	}
+	if !c.API.V1Sunset.IsZero() && c.API.V1Sunset.Before(c.API.V1Deprecation) {
+		return fmt.Errorf("v1 sunset must not be before its deprecation")
+	}
+	if c.Cache.MaxEntries < 0 || c.Cache.MaxBytes < 0 || c.Cache.TTL < 0 || c.Cache.MaxAge < 0 {
+		return fmt.Errorf("response cache limits must not be negative")
+	}
+	if c.Compression.MinSize < 0 {
+		return fmt.Errorf("compression min size must not be negative")
+	}
+	if c.MaxBodyBytes < 0 {
+		return fmt.Errorf("max body bytes must not be negative")
+	}
+	if c.MaxInflatedBytes < 1 {
+		return fmt.Errorf("max inflated bytes must be at least 1")
+	}
+	if c.Processing.Workers < 0 || c.Processing.ParallelMinEpisodes < 0 {
+		return fmt.Errorf("processing workers and parallel min episodes must not be negative")
+	}
+	if c.Search.FuzzyMaxDistance < 0 {
+		return fmt.Errorf("fuzzy max distance must not be negative")
+	}
+	if c.Search.FuzzyMinSimilarity < 0 || c.Search.FuzzyMinSimilarity > 1 {
+		return fmt.Errorf("fuzzy min similarity must be between 0 and 1")
+	}
+	if c.Search.SuggestLimit < 1 {
+		return fmt.Errorf("suggest limit must be at least 1")
+	}
+	if _, err := time.LoadLocation(c.FeedTimezone); err != nil {
+		return fmt.Errorf("unknown feed timezone %s: %w", c.FeedTimezone, err)
+	}
+	if c.Validation.RulesProfile != "" && c.Validation.RulesFile == "" {
+		return fmt.Errorf("rules profile %s needs a rules file", c.Validation.RulesProfile)
+	}
+	return nil
+}
+
+// logLevels maps level names to log levels
+var logLevels = map[string]log.Lvl{
+	"debug": log.DEBUG,
+	"info":  log.INFO,
+	"warn":  log.WARN,
+	"error": log.ERROR,
+	"off":   log.OFF,
+}
+
+// ParseLogLevel maps debug/info/warn/error/off to a log level,
+// an empty name means the default debug level
+func ParseLogLevel(level string) (log.Lvl, error) {
+	if level == "" {
+		return LOG_LEVEL_DEBUG, nil
+	}
+	if lvl, ok := logLevels[strings.ToLower(level)]; ok {
+		return lvl, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
+// LogLevelName is the reverse of ParseLogLevel
+func LogLevelName(level log.Lvl) string {
+	for name, lvl := range logLevels {
+		if lvl == level {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated variable, blanks are dropped
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envReader reads typed variables. A value which doesn't parse leaves
+// the fallback in place and is kept for Validate to report, an unset or
+// empty variable is no error.
+type envReader struct {
+	errs []error
+}
+
+func readEnv[T any](r *envReader, key, kind string, fallback T, parse func(string) (T, error)) T {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := parse(raw)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be %s, got %q", key, kind, raw))
+		return fallback
+	}
+	return value
+}
+
+func (r *envReader) getEnvInt(key string, fallback int) int {
+	return readEnv(r, key, "an integer", fallback, strconv.Atoi)
+}
+
+func (r *envReader) getEnvBool(key string, fallback bool) bool {
+	return readEnv(r, key, "true or false", fallback, strconv.ParseBool)
+}
+
+func (r *envReader) getEnvFloat(key string, fallback float64) float64 {
+	return readEnv(r, key, "a number", fallback, func(raw string) (float64, error) {
+		return strconv.ParseFloat(raw, 64)
+	})
+}
+
+// getEnvTime reads an RFC 3339 time
+func (r *envReader) getEnvTime(key string, fallback time.Time) time.Time {
+	return readEnv(r, key, "an RFC 3339 time", fallback, func(raw string) (time.Time, error) {
+		return time.Parse(time.RFC3339, raw)
+	})
+}
+
+func (r *envReader) getEnvDuration(key string, fallback time.Duration) time.Duration {
+	return readEnv(r, key, "a duration", fallback, time.ParseDuration)
+}
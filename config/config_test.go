@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadReportsUnparsableValues(t *testing.T) {
+	t.Setenv("STAN_EPISODE_SERVER_MAX_BODY_BYTES", "10MB")
+	t.Setenv("STAN_EPISODE_SERVER_RESPONSE_CACHE", "yes please")
+	t.Setenv("STAN_EPISODE_SERVER_READ_TIMEOUT", "30")
+	t.Setenv("STAN_EPISODE_SERVER_LOG_LEVEL", "verbose")
+
+	cfg := Load()
+	assert.Equal(t, Default().MaxBodyBytes, cfg.MaxBodyBytes)
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, `STAN_EPISODE_SERVER_MAX_BODY_BYTES must be an integer, got "10MB"`)
+	assert.ErrorContains(t, err, `STAN_EPISODE_SERVER_RESPONSE_CACHE must be true or false, got "yes please"`)
+	assert.ErrorContains(t, err, `STAN_EPISODE_SERVER_READ_TIMEOUT must be a duration, got "30"`)
+	assert.ErrorContains(t, err, `STAN_EPISODE_SERVER_LOG_LEVEL: unknown log level "verbose"`)
+}
+
+func TestLoadIgnoresEmptyValues(t *testing.T) {
+	t.Setenv("STAN_EPISODE_SERVER_MAX_BODY_BYTES", "")
+	t.Setenv("STAN_EPISODE_SERVER_WORKERS", "4")
+
+	cfg := Load()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 4, cfg.Processing.Workers)
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+// bodyError is a request body which can't be read, status is the response code
+type bodyError struct {
+	status int
+	msg    string
+}
+
+func (e *bodyError) Error() string {
+	return e.msg
+}
+
+// bodyStatus is the response code for a readBody error
+func bodyStatus(err error) int {
+	var bodyErr *bodyError
+	if errors.As(err, &bodyErr) {
+		return bodyErr.status
+	}
+	return http.StatusBadRequest
+}
+
+// errUnreadableBody keeps the long standing answer to a missing or broken body
+var errUnreadableBody = &bodyError{status: http.StatusBadRequest, msg: "JSON parsing failed"}
+
+// readBody reads the whole request body, inflating it when it is sent with
+// Content-Encoding gzip or zstd. An inflated body is held to
+// settings.MaxInflatedBytes, so a small compressed upload can't expand
+// without bound. settings.MaxBodyBytes, when set, caps every body both as
+// sent and once inflated.
+func readBody(c echo.Context) ([]byte, error) {
+	req := c.Request()
+	if req == nil || req.Body == nil {
+		return nil, errUnreadableBody
+	}
+	// the sent body is limited separately so its read error can be told
+	// apart from one in the decoder
+	sent := &limitReader{r: req.Body, limit: math.MaxInt64}
+	if settings.MaxBodyBytes > 0 {
+		sent.limit = settings.MaxBodyBytes
+	}
+
+	var body io.Reader
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get(echo.HeaderContentEncoding)))
+	limit := min(sent.limit, settings.MaxInflatedBytes)
+	switch encoding {
+	case "", "identity":
+		body = sent
+		limit = sent.limit
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(sent)
+		if err != nil {
+			return nil, inflateError(c, sent, limit, err)
+		}
+		defer zr.Close()
+		body = zr
+	case "zstd":
+		// the window is bounded too, a frame could otherwise ask for
+		// far more memory than the body it inflates to
+		zr, err := zstd.NewReader(sent,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(limit)),
+			zstd.WithDecoderMaxWindow(uint64(max(limit, zstd.MinWindowSize))))
+		if err != nil {
+			return nil, inflateError(c, sent, limit, err)
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		c.Logger().Errorf("request body has unsupported Content-Encoding %q", encoding)
+		return nil, &bodyError{
+			status: http.StatusUnsupportedMediaType,
+			msg:    fmt.Sprintf("Content-Encoding %s is not supported, send gzip, zstd or identity", encoding),
+		}
+	}
+
+	if limit < math.MaxInt64 {
+		body = io.LimitReader(body, limit+1)
+	}
+	rawBody, err := io.ReadAll(body)
+	if err != nil {
+		if encoding == "" || encoding == "identity" {
+			if sent.exceeded {
+				return nil, tooLarge(sent.limit)
+			}
+			c.Logger().Errorf("failed to read request body for unmarshaling: %s", err.Error())
+			return nil, errUnreadableBody
+		}
+		return nil, inflateError(c, sent, limit, err)
+	}
+	if int64(len(rawBody)) > limit {
+		c.Logger().Errorf("request body inflates past %d bytes", limit)
+		return nil, tooLarge(limit)
+	}
+	return rawBody, nil
+}
+
+// tooLarge is the error of a body over limit bytes
+func tooLarge(limit int64) *bodyError {
+	return &bodyError{
+		status: http.StatusRequestEntityTooLarge,
+		msg:    fmt.Sprintf("body is larger than %d bytes", limit),
+	}
+}
+
+// inflateError tells a body cut off by a limit from one which is corrupt
+func inflateError(c echo.Context, sent *limitReader, limit int64, err error) error {
+	if sent.exceeded {
+		c.Logger().Errorf("compressed request body is over the limit: %s", err.Error())
+		return tooLarge(sent.limit)
+	}
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		c.Logger().Errorf("compressed request body is over the limit: %s", err.Error())
+		return tooLarge(limit)
+	}
+	c.Logger().Errorf("failed to decompress request body: %s", err.Error())
+	return &bodyError{status: http.StatusBadRequest, msg: "body could not be decompressed"}
+}
+
+// limitReader reads at most limit bytes and remembers when there was more
+type limitReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.read >= l.limit {
+		// one more byte tells a body of exactly limit bytes from a longer one
+		var probe [1]byte
+		if n, _ := io.ReadAtLeast(l.r, probe[:], 1); n > 0 {
+			l.exceeded = true
+			return 0, errors.New("request body is over the limit")
+		}
+		return 0, io.EOF
+	}
+	if rest := l.limit - l.read; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func gzipped(t *testing.T, body string) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(body))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.String()
+}
+
+func zstded(t *testing.T, body string) string {
+	zw, err := zstd.NewWriter(nil)
+	assert.NoError(t, err)
+	defer zw.Close()
+	return string(zw.EncodeAll([]byte(body), nil))
+}
+
+func TestCompressedRequestBodies(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxBodyBytes = 4096
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	plain := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil)
+	assert.Equal(t, http.StatusOK, plain.Code)
+
+	// inflates far past the limit while staying small on the wire
+	bomb := `{"payload": [], "pad": "` + strings.Repeat("a", 1<<20) + `"}`
+
+	tests := []struct {
+		name         string
+		body         string
+		encoding     string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"Plain", cacheBody, "", http.StatusOK, ""},
+		{"Identity", cacheBody, "identity", http.StatusOK, ""},
+		{"Gzip", gzipped(t, cacheBody), "gzip", http.StatusOK, ""},
+		{"X-Gzip", gzipped(t, cacheBody), "x-gzip", http.StatusOK, ""},
+		{"Zstd", zstded(t, cacheBody), "zstd", http.StatusOK, ""},
+		{"Encoding is case insensitive", gzipped(t, cacheBody), "GZIP", http.StatusOK, ""},
+		{"Plain body over the limit", `{"payload": [], "pad": "` + strings.Repeat("a", 4096) + `"}`, "", http.StatusRequestEntityTooLarge,
+			"Could not decode request: body is larger than 4096 bytes"},
+		{"Gzip bomb", gzipped(t, bomb), "gzip", http.StatusRequestEntityTooLarge, "Could not decode request: body is larger than 4096 bytes"},
+		{"Zstd bomb", zstded(t, bomb), "zstd", http.StatusRequestEntityTooLarge, "Could not decode request: body is larger than 4096 bytes"},
+		{"Corrupt gzip", "not gzip at all", "gzip", http.StatusBadRequest, "Could not decode request: body could not be decompressed"},
+		{"Truncated zstd", zstded(t, cacheBody)[:20], "zstd", http.StatusBadRequest, "Could not decode request: body could not be decompressed"},
+		{"Unsupported encoding", cacheBody, "br", http.StatusUnsupportedMediaType,
+			"Could not decode request: Content-Encoding br is not supported, send gzip, zstd or identity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.encoding != "" {
+				header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := post(DealwithEpisodes, "/api/v1/episodes", tt.body, header)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedErr == "" {
+				assert.Equal(t, plain.Body.String(), rec.Body.String())
+				return
+			}
+			var response map[string]any
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedErr, response["error"])
+		})
+	}
+}
+
+func TestBodyLimitDefaults(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxInflatedBytes = 4096
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	// plain bodies stay unlimited unless MaxBodyBytes is set
+	large := `{"payload": [], "pad": "` + strings.Repeat("a", 1<<16) + `"}`
+	assert.Equal(t, http.StatusOK, post(DealwithEpisodes, "/api/v1/episodes", large, nil).Code)
+	assert.Equal(t, http.StatusOK, post(DealwithEpisodes, "/api/v1/episodes", gzipped(t, cacheBody), http.Header{"Content-Encoding": {"gzip"}}).Code)
+
+	rec := post(DealwithEpisodes, "/api/v1/episodes", gzipped(t, large), http.Header{"Content-Encoding": {"gzip"}})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "Could not decode request: body is larger than 4096 bytes", response["error"])
+}
+
+func TestBodyLimitExactSize(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxBodyBytes = int64(len(cacheBody))
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	assert.Equal(t, http.StatusOK, post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil).Code)
+	assert.Equal(t, http.StatusOK, post(DealwithEpisodes, "/api/v1/episodes", gzipped(t, cacheBody), http.Header{"Content-Encoding": {"gzip"}}).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(DealwithEpisodes, "/api/v1/episodes", cacheBody+" ", nil).Code)
+}
+
+func TestCompressedRequestBodiesV2(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxBodyBytes = 4096
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	body := `{"data": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	tests := []struct {
+		name         string
+		body         string
+		encoding     string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"Zstd", zstded(t, body), "zstd", http.StatusOK, ""},
+		{"Too large", gzipped(t, strings.Repeat(" ", 8192)+body), "gzip", http.StatusRequestEntityTooLarge, models.ErrorCodeBodyTooLarge},
+		{"Unsupported encoding", body, "compress", http.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedEncoding},
+		{"Corrupt", body, "gzip", http.StatusBadRequest, models.ErrorCodeInvalidBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(FilterShows, "/api/v2/shows", tt.body, http.Header{"Content-Encoding": {tt.encoding}})
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			var response models.ShowsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			if tt.expectedErr == "" {
+				assert.Len(t, response.Data, 1)
+				return
+			}
+			if assert.Len(t, response.Errors, 1) {
+				assert.Equal(t, tt.expectedErr, response.Errors[0].Code)
+			}
+		})
+	}
+}
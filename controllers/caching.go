@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/config"
+	"stan.com/stantest/lru"
+)
+
+// responseCache keeps successful responses, nil when it's off
+var responseCache *lru.Cache[cachedResponse]
+
+// cachedResponse is a response body as the handler produced it, before
+// anything specific to one request was added
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+func newResponseCache(cfg *config.Config) *lru.Cache[cachedResponse] {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+	return lru.New[cachedResponse](lru.Options{
+		MaxEntries: cfg.Cache.MaxEntries,
+		MaxBytes:   cfg.Cache.MaxBytes,
+		TTL:        cfg.Cache.TTL,
+	})
+}
+
+// CacheStats reports the response cache counters, for expvar
+func CacheStats() any {
+	if responseCache == nil {
+		return map[string]bool{"enabled": false}
+	}
+	return responseCache.Stats()
+}
+
+// timeRelative are the query parameters whose filters compare against
+// now(), a response to them is out of date as soon as it is made
+var timeRelative = []string{"airsWithinDays", "hasUpcoming"}
+
+// responseKey identifies a request by everything its response depends on,
+// the body and query parameters in canonical order and any extra parts.
+// It is empty when the response can't be kept.
+func responseKey(c echo.Context, body []byte, extra ...string) string {
+	for _, name := range timeRelative {
+		if c.QueryParams().Has(name) {
+			return ""
+		}
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", c.Request().Method, c.Request().URL.Path, c.QueryParams().Encode())
+	for _, part := range extra {
+		fmt.Fprintf(hash, "%s\n", part)
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// contentETag is a strong validator of a response body
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// lookupResponse returns the response kept for key
+func lookupResponse(c echo.Context, key string) (cachedResponse, bool) {
+	if responseCache == nil || key == "" {
+		return cachedResponse{}, false
+	}
+	cached, ok := responseCache.Get(key)
+	if ok {
+		c.Logger().Debugf("serving response %s from the cache", cached.etag)
+		c.Response().Header().Set("X-Cache", "HIT")
+	} else {
+		c.Response().Header().Set("X-Cache", "MISS")
+	}
+	return cached, ok
+}
+
+// respond sends value as a 200 response and keeps its body for key. The
+// ETag is the hash of the body unless the handler set one already. finish
+// rewrites the body with what is specific to this request, like its id,
+// after the ETag is computed, it may be nil.
+func respond(c echo.Context, key string, value any, finish func([]byte) ([]byte, error)) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	cached := cachedResponse{etag: c.Response().Header().Get("ETag"), body: body}
+	if cached.etag == "" {
+		cached.etag = contentETag(body)
+	}
+	if responseCache != nil && key != "" {
+		responseCache.Add(key, cached, int64(len(body)))
+	}
+	return sendCached(c, cached, finish)
+}
+
+// sendCached sends a response with its validators, or 304 when the
+// client's copy matches. POST endpoints are queries too, so they answer
+// If-None-Match the same way.
+func sendCached(c echo.Context, cached cachedResponse, finish func([]byte) ([]byte, error)) error {
+	header := c.Response().Header()
+	header.Set("ETag", cached.etag)
+	if header.Get("Cache-Control") == "" {
+		if settings.Cache.MaxAge > 0 {
+			header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(settings.Cache.MaxAge.Seconds())))
+		} else {
+			header.Set("Cache-Control", "no-cache")
+		}
+	}
+	if etagMatches(c, cached.etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	body := cached.body
+	if finish != nil {
+		var err error
+		if body, err = finish(body); err != nil {
+			return err
+		}
+	}
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// etagMatches reports whether If-None-Match lists etag, weak comparison
+// as RFC 9110 asks for
+func etagMatches(c echo.Context, etag string) bool {
+	match := c.Request().Header.Get("If-None-Match")
+	if match == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(match, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
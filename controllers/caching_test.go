@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/logging"
+	"stan.com/stantest/lru"
+	"stan.com/stantest/models"
+)
+
+const cacheBody = `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+
+func post(handler echo.HandlerFunc, path, body string, header http.Header) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec
+}
+
+func TestConditionalRequests(t *testing.T) {
+	first := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.Equal(t, contentETag(first.Body.Bytes()), etag)
+	assert.Equal(t, "no-cache", first.Header().Get("Cache-Control"))
+	assert.Empty(t, first.Header().Get("X-Cache"))
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		header       http.Header
+		expectedCode int
+		sameETag     bool
+	}{
+		{"Same request", "/api/v1/episodes", cacheBody, http.Header{"If-None-Match": {etag}}, http.StatusNotModified, true},
+		{"Among others", "/api/v1/episodes", cacheBody, http.Header{"If-None-Match": {`"x", ` + etag}}, http.StatusNotModified, true},
+		{"Stale ETag", "/api/v1/episodes", cacheBody, http.Header{"If-None-Match": {`"x"`}}, http.StatusOK, true},
+		{"Options change the content", "/api/v1/episodes?fields=seasonCount", cacheBody, http.Header{"If-None-Match": {etag}}, http.StatusOK, false},
+		{"Errors carry no ETag", "/api/v1/episodes", `{`, http.Header{"If-None-Match": {etag}}, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(DealwithEpisodes, tt.path, tt.body, tt.header)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, tt.sameETag, rec.Header().Get("ETag") == etag)
+			if tt.expectedCode == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCacheControlMaxAge(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.MaxAge = 30 * time.Second
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	rec := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil)
+	assert.Equal(t, "private, max-age=30", rec.Header().Get("Cache-Control"))
+}
+
+func TestResponseCache(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.Enabled = true
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	first := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	second := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, nil)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+
+	// a hit still answers conditional requests
+	notModified := post(DealwithEpisodes, "/api/v1/episodes", cacheBody, http.Header{"If-None-Match": {first.Header().Get("ETag")}})
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+
+	other := post(DealwithEpisodes, "/api/v1/episodes?report=true", cacheBody, nil)
+	assert.Equal(t, "MISS", other.Header().Get("X-Cache"))
+	facetsRec := post(FacetEpisodes, "/api/v1/episodes/facets", cacheBody, nil)
+	assert.Equal(t, "MISS", facetsRec.Header().Get("X-Cache"))
+
+	// failures aren't kept
+	post(DealwithEpisodes, "/api/v1/episodes", `{`, nil)
+	failed := post(DealwithEpisodes, "/api/v1/episodes", `{`, nil)
+	assert.Equal(t, http.StatusBadRequest, failed.Code)
+	assert.Equal(t, "MISS", failed.Header().Get("X-Cache"))
+
+	stats := CacheStats().(lru.Stats)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(5), stats.Misses)
+	assert.Equal(t, 3, stats.Entries)
+}
+
+func TestResponseCacheSkipsTimeRelativeFilters(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.Enabled = true
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	fixed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	defer func() { now = time.Now }()
+
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}, "nextEpisode": {"date": "2024-03-03T00:00:00Z"}}]}`
+	for _, query := range []string{"?airsWithinDays=7", "?hasUpcoming=true"} {
+		t.Run(query, func(t *testing.T) {
+			now = func() time.Time { return fixed }
+			first := post(DealwithEpisodes, "/api/v1/episodes"+query, body, nil)
+			assert.Contains(t, first.Body.String(), "show/a")
+
+			// the next episode has aired by the second request
+			now = func() time.Time { return fixed.AddDate(0, 0, 3) }
+			second := post(DealwithEpisodes, "/api/v1/episodes"+query, body, http.Header{"If-None-Match": {first.Header().Get("ETag")}})
+			assert.Equal(t, http.StatusOK, second.Code)
+			assert.Empty(t, second.Header().Get("X-Cache"))
+			assert.NotContains(t, second.Body.String(), "show/a")
+		})
+	}
+	assert.Zero(t, CacheStats().(lru.Stats).Entries)
+}
+
+func TestResponseCacheV2RequestIDs(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cache.Enabled = true
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	body := `{"data": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	send := func(id string) (*httptest.ResponseRecorder, models.ShowsResponse) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/shows", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(logging.RequestIDKey, id)
+		assert.NoError(t, FilterShows(c))
+
+		var response models.ShowsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return rec, response
+	}
+
+	first, firstResponse := send("one")
+	second, secondResponse := send("two")
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	assert.Equal(t, "one", firstResponse.Meta.RequestID)
+	assert.Equal(t, "two", secondResponse.Meta.RequestID)
+	assert.Equal(t, firstResponse.Data, secondResponse.Data)
+
+	// the id is added to the kept body exactly as encoding the whole response would
+	expected, err := json.Marshal(secondResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), second.Body.String())
+}
+
+func TestCacheStatsDisabled(t *testing.T) {
+	assert.Equal(t, map[string]bool{"enabled": false}, CacheStats())
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/models"
+)
+
+// UploadCatalogue replaces the catalogue GET queries run on, the admin
+// server serves it as PUT /admin/catalogue. The upload is checked like the
+// body of a POST request, so queries never fail on a catalogue which was
+// accepted.
+func UploadCatalogue(c echo.Context) error {
+	rawBody, err := readBody(c)
+	if err != nil {
+		return c.JSON(bodyStatus(err), errorBody(c, "Could not decode catalogue: "+err.Error()))
+	}
+	request, failure := decodeEpisodeRequest(c, rawBody)
+	if failure != nil {
+		return c.JSON(http.StatusBadRequest, failure)
+	}
+	if err := CheckCatalogue(request.Payload); err != nil {
+		c.Logger().Errorf("catalogue refused: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Could not decode catalogue: "+err.Error()))
+	}
+
+	snapshot, err := catalogue.Current.Replace(request.Payload)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errorBody(c, err.Error()))
+	}
+	c.Logger().Infof("catalogue %s with %d shows uploaded", snapshot.Version, len(snapshot.Episodes))
+	return c.JSON(http.StatusOK, CatalogueSummary(snapshot))
+}
+
+// CheckCatalogue refuses a catalogue queries would fail on, like one with
+// duplicate slugs under the reject policy
+func CheckCatalogue(episodes []models.Episode) error {
+	if err := rejectDuplicates(episodes, settings.Validation.DuplicatePolicy); err != nil {
+		return fmt.Errorf("%s", errorMessage(err))
+	}
+	return nil
+}
+
+// CatalogueSummary describes a stored catalogue without its shows
+func CatalogueSummary(snapshot *catalogue.Snapshot) map[string]any {
+	if snapshot.Empty() {
+		return map[string]any{"shows": 0}
+	}
+	return map[string]any{
+		"shows":     len(snapshot.Episodes),
+		"version":   snapshot.Version,
+		"updatedAt": snapshot.UpdatedAt,
+	}
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"stan.com/stantest/color"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+// applyColourPolicy canonicalises the primary colour to #rrggbb, invalid
+// colours are handled according to policy
+func applyColourPolicy(episode *models.Episode, policy string) ([]models.ValidationIssue, error) {
+	if episode.PrimaryColor == "" {
+		return nil, nil
+	}
+
+	normalized, err := color.Normalize(episode.PrimaryColor)
+	if err != nil {
+		switch policy {
+		case config.COLOUR_POLICY_REJECT:
+			return nil, &fieldError{field: fieldPrimaryColour, message: "must be a valid colour"}
+		case config.COLOUR_POLICY_DROP:
+			episode.PrimaryColor = ""
+			return []models.ValidationIssue{{Slug: episode.Slug, Field: fieldPrimaryColour, Message: "invalid colour removed"}}, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	if normalized == episode.PrimaryColor {
+		return nil, nil
+	}
+	episode.PrimaryColor = normalized
+	return []models.ValidationIssue{{Slug: episode.Slug, Field: fieldPrimaryColour, Message: "normalised to " + normalized}}, nil
+}
+
+// textColour is the WCAG contrasting text colour for a primary colour,
+// empty when the primary colour is missing or invalid
+func textColour(primary string) string {
+	background, err := color.Parse(primary)
+	if err != nil {
+		return ""
+	}
+	return color.ContrastText(background).Hex()
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestApplyColourPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		colour       string
+		policy       string
+		expected     string
+		wantErr      bool
+		wantModified bool
+	}{
+		{name: "Canonical colour is untouched", colour: "#ff7800", policy: config.COLOUR_POLICY_REJECT, expected: "#ff7800"},
+		{name: "Missing colour is fine", colour: "", policy: config.COLOUR_POLICY_REJECT, expected: ""},
+		{name: "Named colour is normalised", colour: "Red", policy: config.COLOUR_POLICY_DROP, expected: "#ff0000", wantModified: true},
+		{name: "Invalid colour is rejected", colour: "orangeish", policy: config.COLOUR_POLICY_REJECT, wantErr: true},
+		{name: "Invalid colour is dropped", colour: "orangeish", policy: config.COLOUR_POLICY_DROP, expected: "", wantModified: true},
+		{name: "Invalid colour is kept", colour: "orangeish", policy: config.COLOUR_POLICY_KEEP, expected: "orangeish"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			episode := models.Episode{Slug: "show/thetaste", PrimaryColor: tt.colour}
+			issues, err := applyColourPolicy(&episode, tt.policy)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, episode.PrimaryColor)
+			assert.Equal(t, tt.wantModified, len(issues) > 0)
+		})
+	}
+}
+
+func TestTextColour(t *testing.T) {
+	assert.Equal(t, "#000000", textColour("#ff7800"))
+	assert.Equal(t, "#ffffff", textColour("#df0000"))
+	assert.Equal(t, "", textColour("not a colour"))
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+// newFeedLocation loads the configured feed timezone, Validate reports
+// an unknown zone so UTC only stands in for an unchecked config
+func newFeedLocation(cfg *config.Config) *time.Location {
+	loc, err := time.LoadLocation(cfg.FeedTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// readFeedDates reads the episode's dates in loc. Stored catalogues share
+// their episodes between requests, so the dates are read on copies.
+func readFeedDates(episode models.Episode, loc *time.Location) models.Episode {
+	if episode.NextEpisode != nil && episode.NextEpisode.Date.Raw != "" {
+		next := *episode.NextEpisode
+		next.Date = next.Date.In(loc)
+		episode.NextEpisode = &next
+	}
+	cloned := false
+	for i, season := range episode.Seasons {
+		if season.AvailableFrom.Raw == "" && season.AvailableTo.Raw == "" {
+			continue
+		}
+		if !cloned {
+			episode.Seasons = append([]models.Season(nil), episode.Seasons...)
+			cloned = true
+		}
+		episode.Seasons[i].AvailableFrom = season.AvailableFrom.In(loc)
+		episode.Seasons[i].AvailableTo = season.AvailableTo.In(loc)
+	}
+	return episode
+}
+
+// dropUnreadableDates removes dates which were sent but could not be read,
+// the episode is kept without them and each removal is reported
+func dropUnreadableDates(episode *models.Episode) []models.ValidationIssue {
+	var modified []models.ValidationIssue
+	drop := func(field string, date *models.FeedTime) {
+		if date.Raw == "" || date.Valid() {
+			return
+		}
+		modified = append(modified, models.ValidationIssue{
+			Slug:    episode.Slug,
+			Field:   field,
+			Message: fmt.Sprintf("removed unrecognised date %q", date.Raw),
+		})
+		*date = models.FeedTime{}
+	}
+
+	if episode.NextEpisode != nil {
+		drop("nextEpisode.date", &episode.NextEpisode.Date)
+	}
+	for i := range episode.Seasons {
+		drop(fmt.Sprintf("seasons[%d].availableFrom", i), &episode.Seasons[i].AvailableFrom)
+		drop(fmt.Sprintf("seasons[%d].availableTo", i), &episode.Seasons[i].AvailableTo)
+	}
+	return modified
+}
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"stan.com/stantest/config"
+	"stan.com/stantest/logging"
 	"stan.com/stantest/models"
 )
 
@@ -15,92 +19,397 @@ import (
 func DealwithEpisodes(c echo.Context) error {
 	c.Logger().Info("received episode processing request")
 
+	rawBody, err := readBody(c)
+	if err != nil {
+		return c.JSON(bodyStatus(err), errorBody(c, "Could not decode request: "+err.Error()))
+	}
+	key := responseKey(c, rawBody)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, nil)
+	}
+
+	request, failure := decodeEpisodeRequest(c, rawBody)
+	if failure != nil {
+		return c.JSON(http.StatusBadRequest, failure)
+	}
+
+	opts, err := parseRequestOptions(c)
+	if err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+
+	matched, report, err := runEpisodes(c, request.Payload, opts)
+	if errors.Is(err, errCanceled) {
+		return clientGone(c)
+	}
+	if errors.Is(err, errDeadline) {
+		return c.JSON(http.StatusServiceUnavailable, errorBody(c, "Could not process request: processing deadline exceeded"))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Could not decode request: "+err.Error()))
+	}
+
+	var response models.EpisodeResponse
+	for _, result := range matched {
+		response.Response = append(response.Response, result.item)
+	}
+	if opts.Report {
+		response.Report = report
+	}
+
+	// in case no any episodes matched the criteria
+	// just return empty error
+	if len(response.Response) == 0 {
+		c.Logger().Info("no episodes matched the criteria")
+		response.Response = []models.EpisodeResponseItem{}
+	}
+
+	return respond(c, key, response, nil)
+}
+
+// decodeEpisodeRequest validates and decodes the body of a v1 request.
+// When it can't be used the second result is the body of the 400 response.
+func decodeEpisodeRequest(c echo.Context, rawBody []byte) (models.EpisodeRequest, any) {
 	var request models.EpisodeRequest
-	if c.Request() != nil && c.Request().Body != nil {
-		rawBody, err := io.ReadAll(c.Request().Body)
-		if err != nil {
-			c.Logger().Errorf("failed to read request body for unmarshaling: %s", err.Error())
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Could not decode request: JSON parsing failed"})
+	if settings.Validation.Schema {
+		violations, err := episodeSchema.ValidateJSON(rawBody)
+		if err == nil && len(violations) > 0 {
+			c.Logger().Errorf("request does not match the schema: %d violations", len(violations))
+			return request, schemaErrorBody(c, violations)
 		}
+	}
 
-		if err := json.Unmarshal(rawBody, &request); err != nil {
-			c.Logger().Errorf("failed to unmarshal request: %s", err.Error())
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Could not decode request: JSON parsing failed"})
-		}
-	} else {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Could not decode request: JSON parsing failed"})
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		c.Logger().Errorf("failed to unmarshal request: %s", err.Error())
+		return request, errorBody(c, "Could not decode request: JSON parsing failed")
 	}
 
 	// validate request data
 	if err := validateRequest(request); err != nil {
 		c.Logger().Errorf("request validation failed: %s", err.Error())
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Could not decode request: " + err.Error()})
+		return request, errorBody(c, "Could not decode request: "+err.Error())
+	}
+	return request, nil
+}
+
+var (
+	// errDeadline stops processing once the request deadline passed
+	errDeadline = errors.New("processing deadline exceeded")
+	// errCanceled stops processing once the client went away
+	errCanceled = errors.New("client closed the request")
+)
+
+// statusClientClosedRequest is logged for requests the client gave up on,
+// nobody is left to read the response
+const statusClientClosedRequest = 499
+
+// stopped is the error processing stops with once ctx is done
+func stopped(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return errCanceled
 	}
+	return errDeadline
+}
+
+// clientGone ends a request the client gave up on without a body
+func clientGone(c echo.Context) error {
+	c.Logger().Info("client closed the request before it was answered")
+	return c.NoContent(statusClientClosedRequest)
+}
 
-	episodeCount := len(request.Payload)
+// runEpisodes is the processing core shared by every API version. It
+// returns the episodes which matched, in payload order, and the validation
+// report. A payload which can't be processed as a whole returns an error,
+// errDeadline when time ran out and errCanceled when the client went away.
+func runEpisodes(c echo.Context, payload []models.Episode, opts requestOptions) ([]episodeResult, *models.ValidationReport, error) {
+	start := time.Now()
+	episodeCount := len(payload)
 	c.Logger().Infof("processing %d episodes", episodeCount)
 
-	// filter episodes based on our criteria
+	// filter episodes based on our criteria, by default
 	// DRM enabled (drm: true) and at least one episode (episodeCount > 0).
-	var response models.EpisodeResponse
-	matchedCount := 0
+	criteria := opts.Criteria
+	if criteria == nil {
+		criteria = defaultCriteria
+	}
+	var matched []episodeResult
+
+	// duplicates are looked for over the whole payload, a request the
+	// reject policy fails never gets as far as the filters
+	policy := settings.Validation.DuplicatePolicy
+	if err := rejectDuplicates(payload, policy); err != nil {
+		c.Logger().Errorf("request validation failed: %s", err.Error())
+		return nil, nil, err
+	}
+
+	// searches narrow the episodes before anything else
+	var scores map[int]float64
+	if opts.Query != nil || opts.Title != "" {
+		scores = searchEpisodes(payload, opts)
+	}
+
+	ctx := c.Request().Context()
+	aborted := func(err error) error {
+		c.Logger().Warnj(log.JSON{
+			"message":     "episode processing aborted",
+			"error":       err.Error(),
+			"matched":     len(matched),
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		return stopped(ctx)
+	}
+
+	// candidates are the episodes the searches and filters let through,
+	// with where each was in the payload
+	at := now()
+	var candidates []models.Episode
+	var positions []int
+	for i, episode := range payload {
+		if _, found := scores[i]; scores != nil && !found {
+			continue
+		}
+		episode = readFeedDates(episode, feedLocation)
+		if matchesFilters(episode, criteria, at) && matchesFilters(episode, opts.Filters, at) {
+			candidates = append(candidates, episode)
+			positions = append(positions, i)
+		}
+	}
 
-	for _, episode := range request.Payload {
-		c.Logger().Debugf("processing episode: %s", episode.Title)
+	// merged duplicates are validated as one episode, the candidates
+	// are merged so one which was filtered out never fills in another
+	candidates, kept, merged := mergeDuplicates(candidates, policy)
+	report := &models.ValidationReport{
+		Rejected: []models.ValidationIssue{},
+		Modified: append([]models.ValidationIssue{}, merged...),
+		Warnings: []models.ValidationIssue{},
+	}
 
-		if episode.DRM && episode.EpisodeCount > 0 {
-			// validate episode data
-			if err := validateEpisode(episode); err != nil {
-				c.Logger().Warnf("skipping invalid episode %s: %s", episode.Title, err.Error())
-				continue
+	// evaluate runs on the workers too, where a log line per episode
+	// would have them queue on the logger
+	evaluate := func(i int, episode models.Episode) episodeResult {
+		// validate and clean up episode data
+		result := processEpisode(episode, opts)
+		if scores != nil {
+			score := scores[positions[kept[i]]]
+			result.score = score
+			result.item.Score = roundScore(score)
+		}
+		return result
+	}
+	// aggregation counts the first result with each slug; under keep-last
+	// the results are collected last first, so that is the last valid one
+	counted := map[string]bool{}
+	reverse := opts.Aggregate != nil && policy == config.DUPLICATE_POLICY_KEEP_LAST
+	collect := func(episode models.Episode, result episodeResult) {
+		if result.err != nil {
+			c.Logger().Warnj(log.JSON{
+				"message":      "skipping invalid episode",
+				"episode_slug": episode.Slug,
+				"error":        result.err.Error(),
+			})
+			report.Rejected = append(report.Rejected, rejectionIssues(episode.Slug, result.err)...)
+			return
+		}
+		report.Modified = append(report.Modified, result.modified...)
+		report.Warnings = append(report.Warnings, result.warnings...)
+		if opts.Aggregate == nil {
+			matched = append(matched, result)
+		} else if !counted[result.episode.Slug] {
+			counted[result.episode.Slug] = true
+			opts.Aggregate(result.episode)
+		}
+	}
+	// order gives the k-th candidate to collect
+	order := func(k int) int {
+		if reverse {
+			return len(candidates) - 1 - k
+		}
+		return k
+	}
+
+	// large payloads are processed by a pool of workers, the results are
+	// collected in payload order either way, last first for a keep-last count
+	if workers := episodeWorkers(len(candidates)); workers > 1 {
+		c.Logger().Debugf("processing %d episodes with %d workers", len(candidates), workers)
+		results, err := evaluateParallel(ctx, candidates, workers, evaluate)
+		if err != nil {
+			return nil, nil, aborted(err)
+		}
+		for k := range results {
+			i := order(k)
+			collect(candidates[i], results[i])
+		}
+	} else {
+		for k := range candidates {
+			i := order(k)
+			episode := candidates[i]
+			// stop working once the processing deadline passed or the client went away
+			if err := ctx.Err(); err != nil {
+				return nil, nil, aborted(err)
 			}
+			c.Logger().Debugj(log.JSON{
+				"message":      "processing episode",
+				"episode_slug": episode.Slug,
+				"title":        episode.Title,
+			})
+			collect(episode, evaluate(i, episode))
+		}
+	}
+
+	if opts.Aggregate != nil {
+		c.Logger().Infoj(log.JSON{
+			"message":     "aggregated episodes",
+			"episodes":    episodeCount,
+			"matched":     len(counted),
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		return nil, report, nil
+	}
 
-			response.Response = append(response.Response, models.EpisodeResponseItem{
-				Image: episode.Image.ShowImage,
-				Slug:  episode.Slug,
-				Title: episode.Title,
+	// the other policies choose among the duplicates which passed validation
+	var duplicates []models.ValidationIssue
+	matched, duplicates = dropDuplicates(matched, policy)
+	report.Rejected = append(report.Rejected, duplicates...)
+
+	if imageVerifier != nil && len(matched) > 0 {
+		var rejected, warnings []models.ValidationIssue
+		matched, rejected, warnings = verifyImages(ctx, imageVerifier, matched, settings.Validation.ImageCheck.Mode)
+		if err := ctx.Err(); err != nil {
+			c.Logger().Warnj(log.JSON{
+				"message":     "image verification aborted",
+				"error":       err.Error(),
+				"duration_ms": time.Since(start).Milliseconds(),
 			})
-			matchedCount++
+			return nil, nil, stopped(ctx)
 		}
+		report.Rejected = append(report.Rejected, rejected...)
+		report.Warnings = append(report.Warnings, warnings...)
 	}
 
-	c.Logger().Infof("processed %d episodes, %d matched criteria", episodeCount, matchedCount)
+	if scores != nil {
+		rankResults(matched)
+	}
 
-	// in case no any episodes matched the criteria
-	// just return empty error
-	if len(response.Response) == 0 {
-		c.Logger().Info("no episodes matched the criteria")
-		return c.JSON(http.StatusOK, models.EpisodeResponse{Response: []models.EpisodeResponseItem{}})
+	c.Logger().Infoj(log.JSON{
+		"message":     "processed episodes",
+		"episodes":    episodeCount,
+		"matched":     len(matched),
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+	return matched, report, nil
+}
+
+// episodeResult is the outcome of processing one episode
+type episodeResult struct {
+	// episode is the episode after clean up
+	episode models.Episode
+	item    models.EpisodeResponseItem
+	// modified lists the changes made to the episode
+	modified []models.ValidationIssue
+	// warnings flag doubtful values which were let through
+	warnings []models.ValidationIssue
+	// err is set when the episode was rejected
+	err error
+	// score is the relevance to the searches, if there are any
+	score float64
+}
+
+// processEpisode validates and cleans up an episode which matched the criteria
+func processEpisode(episode models.Episode, opts requestOptions) episodeResult {
+	var result episodeResult
+	if result.err = validateEpisode(episode); result.err != nil {
+		return result
+	}
+	result.modified = append(result.modified, dropUnreadableDates(&episode)...)
+	result.modified = append(result.modified, dropSeasonsWithoutSlug(&episode)...)
+	result.modified = append(result.modified, canonicalizeSlug(&episode)...)
+	result.modified = append(result.modified, applyURLPolicy(&episode)...)
+
+	modified, err := applyHTMLPolicy(&episode, settings.Validation.HTMLPolicy)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.modified = append(result.modified, modified...)
+
+	modified, err = applyColourPolicy(&episode, settings.Validation.ColourPolicy)
+	if err != nil {
+		result.err = err
+		return result
 	}
+	result.modified = append(result.modified, modified...)
+
+	modified, warnings := normalizeVocabularies(&episode)
+	result.modified = append(result.modified, modified...)
+	result.warnings = append(result.warnings, warnings...)
 
-	return c.JSON(http.StatusOK, response)
+	// declared rules see the cleaned up episode
+	warnings, err = applyRules(episode, opts.Profile)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.warnings = append(result.warnings, warnings...)
+
+	result.episode = episode
+	result.item = buildResponseItem(episode, opts)
+	return result
 }
 
-// payload must be not empty
-func validateRequest(request models.EpisodeRequest) error {
-	if request.Payload == nil {
-		return fmt.Errorf("payload is required")
+// errorBody builds the error response, tagged with the request id when we have one
+func errorBody(c echo.Context, message string) map[string]string {
+	body := map[string]string{"error": message}
+	if id := logging.RequestID(c); id != "" {
+		body["request_id"] = id
 	}
-	return nil
+	return body
 }
 
-// validate an URL
-func isValidURL(checkUrl string) bool {
-	_, err := url.ParseRequestURI(checkUrl)
-	if err != nil {
-		return false
+// HTTPErrorHandler answers the errors handlers return and echo raises
+// itself, unknown routes and methods, middleware refusals and recovered
+// panics, with the same body as handler errors so they carry the request id
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	he, ok := err.(*echo.HTTPError)
+	if ok {
+		if internal, ok := he.Internal.(*echo.HTTPError); ok {
+			he = internal
+		}
+	} else {
+		// the cause stays in the log, not in the response
+		he = echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if he.Code >= http.StatusInternalServerError {
+		c.Logger().Error(err)
 	}
 
-	u, err := url.Parse(checkUrl)
-	if err != nil || u.Scheme == "" || u.Host == "" {
-		return false
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(he.Code)
+	} else {
+		err = c.JSON(he.Code, errorBody(c, errorMessage(he)))
 	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
 
-	if u.Scheme != "https" && u.Scheme != "http" {
-		return false
+// errorMessage returns the message of a validation error without the status code
+func errorMessage(err error) string {
+	if he, ok := err.(*echo.HTTPError); ok {
+		return fmt.Sprint(he.Message)
 	}
+	return err.Error()
+}
 
-	return true
+// payload must be not empty
+func validateRequest(request models.EpisodeRequest) error {
+	if request.Payload == nil {
+		return fmt.Errorf("payload is required")
+	}
+	return nil
 }
 
 // validateEpisode checks if an episode has all required fields and valid values
@@ -111,11 +420,77 @@ func validateEpisode(episode models.Episode) error {
 	if episode.Slug == "" {
 		return echo.NewHTTPError(400, "slug is required")
 	}
+	if err := validateSlug(canonicalSlug(episode.Slug), settings.Validation.SlugPrefix); err != nil {
+		return err
+	}
 	if episode.Image.ShowImage == "" {
 		return echo.NewHTTPError(400, "image.showImage is required")
 	}
-	if !isValidURL(episode.Image.ShowImage) {
-		return echo.NewHTTPError(400, "image.showImage must be a valid URL")
+	if err := checkURL("image.showImage", episode.Image.ShowImage, true); err != nil {
+		return err
+	}
+	if err := validateImageSet(episode.Image); err != nil {
+		return err
+	}
+	if err := validateNextEpisode(episode.NextEpisode); err != nil {
+		return err
+	}
+	if err := validateSeasons(episode); err != nil {
+		return err
 	}
 	return nil
 }
+
+// validateNextEpisode checks the optional fields which are present
+func validateNextEpisode(next *models.NextEpisode) error {
+	if next == nil {
+		return nil
+	}
+	if next.URL != "" {
+		if err := checkURL("nextEpisode.url", next.URL, false); err != nil {
+			return err
+		}
+	}
+	if next.ChannelLogo != "" {
+		if err := checkURL("nextEpisode.channelLogo", next.ChannelLogo, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildResponseItem projects an episode onto the response,
+// optional fields are only set when requested
+func buildResponseItem(episode models.Episode, opts requestOptions) models.EpisodeResponseItem {
+	item := models.EpisodeResponseItem{
+		Image: selectImage(episode.Image, opts.ImageRole, opts.MaxWidth),
+		Slug:  episode.Slug,
+		Title: episode.Title,
+	}
+	if opts.Fields[fieldNextEpisode] {
+		item.NextEpisode = episode.NextEpisode
+	}
+	if opts.Fields[fieldSeasons] {
+		item.Seasons = episode.Seasons
+	}
+	if opts.Fields[fieldSeasonCount] {
+		seasonCount := len(episode.Seasons)
+		item.SeasonCount = &seasonCount
+	}
+	if opts.Fields[fieldPrimaryColour] {
+		item.PrimaryColor = episode.PrimaryColor
+	}
+	if opts.Fields[fieldTextColour] {
+		item.TextColour = textColour(episode.PrimaryColor)
+	}
+	if opts.Fields[fieldCountry] {
+		item.Country = episode.Country
+	}
+	if opts.Fields[fieldLanguage] {
+		item.Language = episode.Language
+	}
+	if opts.Fields[fieldGenre] {
+		item.Genre = episode.Genre
+	}
+	return item
+}
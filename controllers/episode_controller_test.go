@@ -2,13 +2,17 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/logging"
 	"stan.com/stantest/models"
 )
 
@@ -504,6 +508,67 @@ func TestValidateEpisode(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Invalid next episode URL",
+			episode: models.Episode{
+				Title:       "The Taste",
+				Slug:        "show/thetaste",
+				Image:       models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/TheTaste1280.jpg"},
+				NextEpisode: &models.NextEpisode{URL: "go.ninemsn.com.au"},
+			},
+			wantErr: true,
+			errMsg:  "nextEpisode.url must be a valid URL",
+		},
+		{
+			name: "Invalid next episode channel logo",
+			episode: models.Episode{
+				Title:       "The Taste",
+				Slug:        "show/thetaste",
+				Image:       models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/TheTaste1280.jpg"},
+				NextEpisode: &models.NextEpisode{ChannelLogo: "ftp://catchup.ninemsn.com.au/img/player/logo_go.gif"},
+			},
+			wantErr: true,
+			errMsg:  "nextEpisode.channelLogo must be a valid URL",
+		},
+		{
+			name: "Unrecognised next episode date is left to processing",
+			episode: models.Episode{
+				Title:       "The Taste",
+				Slug:        "show/thetaste",
+				Image:       models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/TheTaste1280.jpg"},
+				NextEpisode: &models.NextEpisode{Date: models.FeedTime{Raw: "next tuesday"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Slug with invalid characters",
+			episode: models.Episode{
+				Title: "Thunderbirds",
+				Slug:  "show/thunder birds!",
+				Image: models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/Thunderbirds_1280.jpg"},
+			},
+			wantErr: true,
+			errMsg:  "slug may only contain",
+		},
+		{
+			name: "Slug without show prefix",
+			episode: models.Episode{
+				Title: "Thunderbirds",
+				Slug:  "thunderbirds",
+				Image: models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/Thunderbirds_1280.jpg"},
+			},
+			wantErr: true,
+			errMsg:  `slug must start with "show/"`,
+		},
+		{
+			name: "Slug needing canonicalisation",
+			episode: models.Episode{
+				Title: "Thunderbirds",
+				Slug:  " Show//Thunderbirds/ ",
+				Image: models.Image{ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/Thunderbirds_1280.jpg"},
+			},
+			wantErr: false,
+		},
 		{
 			name: "Valid image URL with path",
 			episode: models.Episode{
@@ -529,3 +594,120 @@ func TestValidateEpisode(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorBodyCarriesRequestID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewBufferString(`{invalid json}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(logging.RequestIDKey, "req-42")
+
+	err := DealwithEpisodes(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var errorResponse map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "req-42", errorResponse["request_id"])
+}
+
+func TestHTTPErrorHandlerCarriesRequestID(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(logging.RequestIDKey, "req-42")
+			return next(c)
+		}
+	})
+	e.POST("/api/v1/episodes", DealwithEpisodes)
+	e.GET("/failing", func(echo.Context) error {
+		return errors.New("database password in here")
+	})
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		expectedCode    int
+		expectedMessage string
+	}{
+		{name: "Unknown route", method: http.MethodGet, path: "/nowhere", expectedCode: http.StatusNotFound, expectedMessage: "Not Found"},
+		{name: "Wrong method", method: http.MethodGet, path: "/api/v1/episodes", expectedCode: http.StatusMethodNotAllowed, expectedMessage: "Method Not Allowed"},
+		{name: "Internal error", method: http.MethodGet, path: "/failing", expectedCode: http.StatusInternalServerError, expectedMessage: "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			var body map[string]string
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, map[string]string{"error": tt.expectedMessage, "request_id": "req-42"}, body)
+		})
+	}
+}
+
+func TestClientGoneIsNotAnswered(t *testing.T) {
+	useCatalogue(t, facetPayload)
+	v1 := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A",
+		"image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	v2 := `{"data": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A",
+		"image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		method  string
+		path    string
+		body    string
+	}{
+		{"Episodes", DealwithEpisodes, http.MethodPost, "/api/v1/episodes", v1},
+		{"Shows", ListShows, http.MethodGet, "/api/v1/shows", ""},
+		{"Facets", FacetEpisodes, http.MethodPost, "/api/v1/episodes/facets", v1},
+		{"Catalogue facets", FacetShows, http.MethodGet, "/api/v1/shows/facets", ""},
+		{"v2", FilterShows, http.MethodPost, "/api/v2/shows", v2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)).WithContext(ctx)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, tt.handler(e.NewContext(req, rec)))
+			assert.Equal(t, statusClientClosedRequest, rec.Code)
+			assert.Empty(t, rec.Body.String())
+		})
+	}
+}
+
+func TestDealwithEpisodesRespectsDeadline(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A",
+		"image": {"showImage": "http://catchup.ninemsn.com.au/img/a.jpg"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	// deadline already passed before processing starts
+	ctx, cancel := context.WithTimeout(req.Context(), -time.Second)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := DealwithEpisodes(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var errorResponse map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Contains(t, errorResponse["error"], "processing deadline exceeded")
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/facets"
+	"stan.com/stantest/models"
+)
+
+// facetsParams are the query parameters GET /api/v1/shows/facets
+// understands, the filters of GET /api/v1/shows and what to count
+var facetsParams = []string{
+	"drm", "minEpisodes", "q", "title",
+	"airsWithinDays", "hasUpcoming", "minSeasons", "latestSeasonDrm", "country", "language", "genre",
+	"facets", "pivot", "episodeBuckets",
+}
+
+// FacetEpisodes counts the posted episodes matching the query parameters
+// by facet. Unlike DealwithEpisodes drm and minEpisodes don't default, so
+// the drm facet counts both kinds.
+func FacetEpisodes(c echo.Context) error {
+	c.Logger().Info("received episode facet request")
+
+	if err := checkParams(c, facetsParams); err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+	rawBody, err := readBody(c)
+	if err != nil {
+		return c.JSON(bodyStatus(err), errorBody(c, "Could not decode request: "+err.Error()))
+	}
+	key := responseKey(c, rawBody)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, nil)
+	}
+
+	request, failure := decodeEpisodeRequest(c, rawBody)
+	if failure != nil {
+		return c.JSON(http.StatusBadRequest, failure)
+	}
+	opts, aggregator, err := parseFacetRequest(c)
+	if err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+
+	err = aggregateEpisodes(c, request.Payload, opts, aggregator)
+	if errors.Is(err, errCanceled) {
+		return clientGone(c)
+	}
+	if errors.Is(err, errDeadline) {
+		return c.JSON(http.StatusServiceUnavailable, errorBody(c, "Could not process request: processing deadline exceeded"))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Could not decode request: "+err.Error()))
+	}
+	return respond(c, key, aggregator.Result(), nil)
+}
+
+// FacetShows counts the stored catalogue by facet, see FacetEpisodes
+func FacetShows(c echo.Context) error {
+	c.Logger().Info("received catalogue facet request")
+
+	if err := checkParams(c, facetsParams); err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+	opts, aggregator, err := parseFacetRequest(c)
+	if err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+
+	snapshot := catalogue.Current.Snapshot()
+	if snapshot.Empty() {
+		return c.JSON(http.StatusNotFound, errorBody(c, "No catalogue has been uploaded"))
+	}
+	setCatalogueCacheControl(c)
+	key := responseKey(c, nil, snapshot.Version)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, nil)
+	}
+
+	opts.IndexKey = snapshot.Version
+	err = aggregateEpisodes(c, snapshot.Episodes, opts, aggregator)
+	if errors.Is(err, errCanceled) {
+		return clientGone(c)
+	}
+	if errors.Is(err, errDeadline) {
+		return c.JSON(http.StatusServiceUnavailable, errorBody(c, "Could not process request: processing deadline exceeded"))
+	}
+	if err != nil {
+		// the catalogue was accepted on upload, this is on us
+		c.Logger().Errorf("stored catalogue can't be processed: %s", err.Error())
+		return c.JSON(http.StatusInternalServerError, errorBody(c, "Could not process catalogue: "+err.Error()))
+	}
+	return respond(c, key, aggregator.Result(), nil)
+}
+
+// parseFacetRequest reads the filters and what to count
+func parseFacetRequest(c echo.Context) (requestOptions, *facets.Aggregator, error) {
+	opts, err := parseRequestOptions(c)
+	if err != nil {
+		return opts, nil, err
+	}
+	if err := parseCriteria(c, &opts, false); err != nil {
+		return opts, nil, err
+	}
+
+	var facetOpts facets.Options
+	if value, ok := c.QueryParams()["facets"]; ok {
+		facetOpts.Facets = []string{}
+		if value[0] != "" {
+			facetOpts.Facets = splitList(value[0])
+		}
+	}
+	if value := c.QueryParam("pivot"); value != "" {
+		facetOpts.Pivot = splitList(value)
+	}
+	if value := c.QueryParam("episodeBuckets"); value != "" {
+		for _, bound := range splitList(value) {
+			n, err := strconv.Atoi(bound)
+			if err != nil {
+				return opts, nil, fmt.Errorf("episodeBuckets must be a comma separated list of integers")
+			}
+			facetOpts.EpisodeBuckets = append(facetOpts.EpisodeBuckets, n)
+		}
+	}
+
+	aggregator, err := facets.New(facetOpts)
+	return opts, aggregator, err
+}
+
+func splitList(value string) []string {
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
+
+// aggregateEpisodes counts the episodes runEpisodes matches for the same
+// filters as they are processed, so duplicates are counted once and
+// invalid episodes not at all. Images are not fetched for a count. It
+// returns errDeadline when time ran out and errCanceled when the client
+// went away.
+func aggregateEpisodes(c echo.Context, episodes []models.Episode, opts requestOptions, aggregator *facets.Aggregator) error {
+	opts.Aggregate = aggregator.Add
+	_, _, err := runEpisodes(c, episodes, opts)
+	return err
+}
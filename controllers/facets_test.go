@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/imagecheck"
+	"stan.com/stantest/models"
+)
+
+const facetPayload = `{"payload": [
+	{"drm": true, "episodeCount": 3, "slug": "show/a", "image": {"showImage": "http://img.example.com/a.jpg"}, "title": "Kids Kitchen", "genre": "Reality", "country": "UK", "tvChannel": "GEM"},
+	{"drm": true, "episodeCount": 24, "slug": "show/b", "image": {"showImage": "http://img.example.com/b.jpg"}, "title": "Home Rescue", "genre": "Reality", "country": "AU", "tvChannel": "Nine"},
+	{"drm": false, "episodeCount": 0, "slug": "show/c", "image": {"showImage": "http://img.example.com/c.jpg"}, "title": "Kids Club", "genre": "Drama", "country": "UK"},
+	{"drm": true, "episodeCount": 8, "slug": "show/d", "image": {"showImage": "http://img.example.com/d.jpg"}, "title": "Night Shift", "genre": "Drama", "country": "UK", "tvChannel": "GEM"}
+]}`
+
+func TestFacetEpisodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedTotal int
+		expectedDRM   []models.FacetBucket
+		expectedPivot *models.FacetPivot
+		expectedError string
+	}{
+		{
+			name:          "Everything counts without filters",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 4,
+			expectedDRM:   []models.FacetBucket{{Value: "true", Count: 3}, {Value: "false", Count: 1}},
+		},
+		{
+			name:          "Filters",
+			query:         "?drm=true&country=GB",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 2,
+			expectedDRM:   []models.FacetBucket{{Value: "true", Count: 2}},
+		},
+		{
+			name:          "Text search",
+			query:         "?q=kids",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 2,
+			// ties are in value order
+			expectedDRM: []models.FacetBucket{{Value: "false", Count: 1}, {Value: "true", Count: 1}},
+		},
+		{
+			name:          "DRM shows per genre per country",
+			query:         "?drm=true&facets=drm&pivot=genre,country",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 3,
+			expectedDRM:   []models.FacetBucket{{Value: "true", Count: 3}},
+			expectedPivot: &models.FacetPivot{
+				Facets: []string{"genre", "country"},
+				Buckets: []models.FacetBucket{
+					{Value: "reality", Count: 2, Pivot: []models.FacetBucket{{Value: "AU", Count: 1}, {Value: "GB", Count: 1}}},
+					{Value: "drama", Count: 1, Pivot: []models.FacetBucket{{Value: "GB", Count: 1}}},
+				},
+			},
+		},
+		{
+			name:          "Unknown parameter",
+			query:         "?drms=true",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: unknown parameter drms",
+		},
+		{
+			name:          "Unknown facet",
+			query:         "?facets=colour",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: unknown facet colour, facets are [genre country language tvChannel drm episodeCount]",
+		},
+		{
+			name:          "Invalid buckets",
+			query:         "?episodeBuckets=1,x",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: episodeBuckets must be a comma separated list of integers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes/facets"+tt.query, bytes.NewBufferString(facetPayload))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, FacetEpisodes(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedError != "" {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedError, body["error"])
+				return
+			}
+
+			var response models.FacetsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedTotal, response.Total)
+			assert.Equal(t, tt.expectedDRM, response.Facets["drm"].Buckets)
+			assert.Equal(t, tt.expectedPivot, response.Pivot)
+			assert.Equal(t, tt.expectedTotal, response.EpisodeCount.Count)
+		})
+	}
+}
+
+func TestFacetEpisodesInvalidBody(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes/facets", bytes.NewBufferString(`{"payload": [`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, FacetEpisodes(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestFacetEpisodesCountsProcessedEpisodes(t *testing.T) {
+	// show/a twice, and show/e without a title, which is rejected
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 3, "slug": "show/a", "title": "Kids Kitchen", "genre": "Reality", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 5, "slug": "Show/A", "title": "Kids Kitchen", "genre": "Reality", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 8, "slug": "show/d", "title": "Night Shift", "genre": "Drama", "image": {"showImage": "http://img.example.com/d.jpg"}},
+		{"drm": true, "episodeCount": 2, "slug": "show/e", "genre": "Drama", "image": {"showImage": "http://img.example.com/e.jpg"}}
+	]}`
+	rec := post(FacetEpisodes, "/api/v1/episodes/facets?facets=genre", body, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response models.FacetsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, []models.FacetBucket{{Value: "drama", Count: 1}, {Value: "reality", Count: 1}}, response.Facets["genre"].Buckets)
+	assert.Equal(t, 11, response.EpisodeCount.Sum)
+
+	t.Run("Keep last", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Validation.DuplicatePolicy = config.DUPLICATE_POLICY_KEEP_LAST
+		Configure(cfg)
+		defer Configure(config.Default())
+
+		rec := post(FacetEpisodes, "/api/v1/episodes/facets?facets=genre", body, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response models.FacetsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Total)
+		assert.Equal(t, 13, response.EpisodeCount.Sum)
+	})
+}
+
+func TestFacetEpisodesSkipImageCheck(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	// the test server listens on loopback
+	cfg.Validation.URL.RejectIPLiterals = false
+	cfg.Validation.URL.RejectPrivate = false
+	cfg.Validation.ImageCheck.Mode = config.IMAGE_CHECK_DROP
+	Configure(cfg)
+	defer Configure(config.Default())
+	imageVerifier = imagecheck.New(server.Client(), imagecheck.Options{Concurrency: 2})
+
+	body := fmt.Sprintf(`{"payload": [
+		{"drm": true, "episodeCount": 3, "slug": "show/a", "title": "A", "image": {"showImage": "%[1]s/a.jpg"}},
+		{"drm": true, "episodeCount": 8, "slug": "show/b", "title": "B", "image": {"showImage": "%[1]s/b.jpg"}}
+	]}`, server.URL)
+	rec := post(FacetEpisodes, "/api/v1/episodes/facets", body, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// every image is missing, yet both shows count
+	var response models.FacetsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+	assert.Zero(t, fetches.Load())
+}
+
+func TestFacetShows(t *testing.T) {
+	useCatalogue(t, facetPayload)
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedStats models.Stats
+	}{
+		{
+			name:         "Stats over the catalogue",
+			expectedCode: http.StatusOK,
+			expectedStats: models.Stats{
+				Count: 4, Sum: 35, Min: ptr(0), Max: ptr(24), Avg: ptr(8.75),
+			},
+		},
+		{
+			name:          "Nothing matches",
+			query:         "?minEpisodes=100",
+			expectedCode:  http.StatusOK,
+			expectedStats: models.Stats{},
+		},
+		{
+			name:         "Unknown parameter",
+			query:        "?fields=seasons",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/shows/facets"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, FacetShows(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response models.FacetsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStats, response.EpisodeCount)
+			assert.NotEmpty(t, rec.Header().Get("ETag"))
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"time"
+
+	"stan.com/stantest/models"
+)
+
+// now is swappable so schedule filters can be tested
+var now = time.Now
+
+// episodeFilter decides whether an episode belongs in the response,
+// at is the time the request is evaluated at
+type episodeFilter func(episode models.Episode, at time.Time) bool
+
+// matchesFilters reports whether the episode passes every filter
+func matchesFilters(episode models.Episode, filters []episodeFilter, at time.Time) bool {
+	for _, filter := range filters {
+		if !filter(episode, at) {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultCriteria are what every matched episode needs unless a request
+// brings its own: DRM enabled and at least one episode
+var defaultCriteria = []episodeFilter{drmIs(true), minEpisodes(1)}
+
+// drmIs keeps episodes whose DRM flag is as wanted
+func drmIs(want bool) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		return episode.DRM == want
+	}
+}
+
+// minEpisodes keeps episodes with at least n episodes
+func minEpisodes(n int) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		return episode.EpisodeCount >= n
+	}
+}
+
+// upcomingDate returns the date of the next episode if it is known and not in the past
+func upcomingDate(episode models.Episode, at time.Time) (time.Time, bool) {
+	if episode.NextEpisode == nil || !episode.NextEpisode.Date.Valid() {
+		return time.Time{}, false
+	}
+	date := episode.NextEpisode.Date.Time
+	if date.Before(at) {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// airsWithin keeps episodes whose next episode airs within the window from now
+func airsWithin(window time.Duration) episodeFilter {
+	return func(episode models.Episode, at time.Time) bool {
+		date, ok := upcomingDate(episode, at)
+		return ok && !date.After(at.Add(window))
+	}
+}
+
+// hasUpcoming keeps episodes with (or, if want is false, without) a known upcoming episode
+func hasUpcoming(want bool) episodeFilter {
+	return func(episode models.Episode, at time.Time) bool {
+		_, ok := upcomingDate(episode, at)
+		return ok == want
+	}
+}
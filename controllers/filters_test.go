@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestScheduleFilters(t *testing.T) {
+	fixed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	defer func() { now = time.Now }()
+
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/tomorrow", "title": "Tomorrow",
+			"image": {"showImage": "http://img.example.com/a.jpg"},
+			"nextEpisode": {"date": "2024-03-02T12:00:00Z", "url": "http://go.ninemsn.com.au/"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/nextmonth", "title": "Next Month",
+			"image": {"showImage": "http://img.example.com/b.jpg"},
+			"nextEpisode": {"date": "2024-04-01"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/aired", "title": "Aired",
+			"image": {"showImage": "http://img.example.com/c.jpg"},
+			"nextEpisode": {"date": "2024-02-01"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/none", "title": "None",
+			"image": {"showImage": "http://img.example.com/d.jpg"},
+			"nextEpisode": null}
+	]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSlugs  []string
+	}{
+		{
+			name:           "No schedule filter",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/tomorrow", "show/nextmonth", "show/aired", "show/none"},
+		},
+		{
+			name:           "Airs within the next week",
+			query:          "?airsWithinDays=7",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/tomorrow"},
+		},
+		{
+			name:           "Has upcoming episode",
+			query:          "?hasUpcoming=true",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/tomorrow", "show/nextmonth"},
+		},
+		{
+			name:           "Has no upcoming episode",
+			query:          "?hasUpcoming=false",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/aired", "show/none"},
+		},
+		{
+			name:           "Invalid days",
+			query:          "?airsWithinDays=soon",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown field",
+			query:          "?fields=cast",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+				assert.Nil(t, item.NextEpisode)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+		})
+	}
+}
+
+func TestNextEpisodeProjection(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A",
+		"image": {"showImage": "http://img.example.com/a.jpg"},
+		"nextEpisode": {"date": "2024-03-02 20:30", "url": "http://go.ninemsn.com.au/"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?fields=nextEpisode", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, DealwithEpisodes(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"nextEpisode":{`)
+	assert.Contains(t, rec.Body.String(), `"date":"2024-03-02T20:30:00Z"`)
+}
+
+func TestFeedDatesInConfiguredTimezone(t *testing.T) {
+	cfg := config.Default()
+	cfg.FeedTimezone = "Australia/Sydney"
+	if cfg.Validate() != nil {
+		t.Skip("timezone database not available")
+	}
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	e := echo.New()
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A",
+		"image": {"showImage": "http://img.example.com/a.jpg"},
+		"nextEpisode": {"date": "2024-03-02 20:30", "url": "http://go.ninemsn.com.au/"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?fields=nextEpisode", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, DealwithEpisodes(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"date":"2024-03-02T20:30:00+11:00"`)
+}
+
+func TestUnreadableDatesAreDropped(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 2, "slug": "show/a", "title": "A",
+			"image": {"showImage": "http://img.example.com/a.jpg"},
+			"nextEpisode": {"date": "next tuesday", "url": "http://go.ninemsn.com.au/"},
+			"seasons": [{"slug": "show/a/season/1", "number": 1, "episodeCount": 2, "availableFrom": "soon"}]},
+		{"drm": true, "episodeCount": 1, "slug": "show/b", "title": "B",
+			"image": {"showImage": "http://img.example.com/b.jpg"},
+			"nextEpisode": {"date": "", "url": "http://go.ninemsn.com.au/"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?report=true&fields=nextEpisode", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, DealwithEpisodes(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Response []struct {
+			Slug        string `json:"slug"`
+			NextEpisode struct {
+				Date *string `json:"date"`
+			} `json:"nextEpisode"`
+		} `json:"response"`
+		Report models.ValidationReport `json:"report"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Len(t, response.Response, 2)
+	// the show is kept without the date it sent
+	assert.Equal(t, "show/a", response.Response[0].Slug)
+	assert.Nil(t, response.Response[0].NextEpisode.Date)
+	// an empty date is passed on as sent
+	if assert.NotNil(t, response.Response[1].NextEpisode.Date) {
+		assert.Equal(t, "", *response.Response[1].NextEpisode.Date)
+	}
+
+	assert.Empty(t, response.Report.Rejected)
+	var fields []string
+	for _, issue := range response.Report.Modified {
+		fields = append(fields, issue.Field)
+	}
+	assert.Equal(t, []string{"nextEpisode.date", "seasons[0].availableFrom"}, fields)
+}
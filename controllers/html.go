@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+	"stan.com/stantest/sanitize"
+)
+
+// applyHTMLPolicy cleans the markup carrying fields of an episode according
+// to the configured policy. Changes are returned as issues for the report,
+// with the reject policy unsafe markup fails the episode instead. The
+// episode is a copy, so the decoded request keeps what was sent.
+func applyHTMLPolicy(episode *models.Episode, policy string) ([]models.ValidationIssue, error) {
+	var issues []models.ValidationIssue
+
+	clean := func(field string, value *string) error {
+		if *value == "" {
+			return nil
+		}
+		switch policy {
+		case config.HTML_POLICY_REJECT:
+			if !sanitize.Default.Safe(*value) {
+				return &fieldError{field: field, message: "contains unsafe markup"}
+			}
+		case config.HTML_POLICY_TEXT:
+			if text := sanitize.Text(*value); text != *value {
+				*value = text
+				issues = append(issues, models.ValidationIssue{Slug: episode.Slug, Field: field, Message: "converted to plain text"})
+			}
+		default:
+			if sanitized, modified := sanitize.Default.Sanitize(*value); modified {
+				*value = sanitized
+				issues = append(issues, models.ValidationIssue{Slug: episode.Slug, Field: field, Message: "unsafe markup removed"})
+			}
+		}
+		return nil
+	}
+
+	if err := clean("description", &episode.Description); err != nil {
+		return nil, err
+	}
+	if episode.NextEpisode != nil {
+		// never write through to the decoded request
+		next := *episode.NextEpisode
+		episode.NextEpisode = &next
+		if err := clean("nextEpisode.html", &next.HTML); err != nil {
+			return nil, err
+		}
+	}
+	return issues, nil
+}
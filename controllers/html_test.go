@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestHTMLPolicy(t *testing.T) {
+	defer Configure(config.Default())
+
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/unsafe", "title": "Unsafe",
+			"image": {"showImage": "http://img.example.com/a.jpg"},
+			"nextEpisode": {"html": "<span onclick=\"x()\">Visit</span><script>steal()</script>"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/safe", "title": "Safe",
+			"image": {"showImage": "http://img.example.com/b.jpg"},
+			"nextEpisode": {"html": "<br><span class=\"visit\">Visit the Official Website</span>"}}
+	]}`
+
+	tests := []struct {
+		name             string
+		policy           string
+		expectedSlugs    []string
+		expectedHTML     string
+		expectedRejected int
+		expectedModified int
+	}{
+		{
+			name:             "Sanitize",
+			policy:           config.HTML_POLICY_SANITIZE,
+			expectedSlugs:    []string{"show/unsafe", "show/safe"},
+			expectedHTML:     "<span>Visit</span>",
+			expectedModified: 1,
+		},
+		{
+			name:             "Reject",
+			policy:           config.HTML_POLICY_REJECT,
+			expectedSlugs:    []string{"show/safe"},
+			expectedRejected: 1,
+		},
+		{
+			name:             "Plain text",
+			policy:           config.HTML_POLICY_TEXT,
+			expectedSlugs:    []string{"show/unsafe", "show/safe"},
+			expectedHTML:     "Visit",
+			expectedModified: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.HTMLPolicy = tt.policy
+			Configure(cfg)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?report=true&fields=nextEpisode", bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			if tt.expectedHTML != "" {
+				assert.Equal(t, tt.expectedHTML, response.Response[0].NextEpisode.HTML)
+			}
+
+			assert.NotNil(t, response.Report)
+			assert.Len(t, response.Report.Rejected, tt.expectedRejected)
+			assert.Len(t, response.Report.Modified, tt.expectedModified)
+			for _, issue := range response.Report.Modified {
+				assert.Equal(t, "nextEpisode.html", issue.Field)
+			}
+		})
+	}
+}
+
+func TestHTMLPolicyOnDescription(t *testing.T) {
+	defer Configure(config.Default())
+
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/unsafe", "title": "Unsafe",
+			"description": "<b onclick=\"x()\">International</b> Rescue<script>steal()</script>",
+			"image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/plain", "title": "Plain",
+			"description": "What's life like?",
+			"image": {"showImage": "http://img.example.com/b.jpg"}}
+	]}`
+
+	tests := []struct {
+		name            string
+		policy          string
+		expectedSlugs   []string
+		expectedIssue   string
+		expectedMessage string
+	}{
+		{
+			name:            "Sanitize",
+			policy:          config.HTML_POLICY_SANITIZE,
+			expectedSlugs:   []string{"show/unsafe", "show/plain"},
+			expectedIssue:   "modified",
+			expectedMessage: "unsafe markup removed",
+		},
+		{
+			name:            "Reject",
+			policy:          config.HTML_POLICY_REJECT,
+			expectedSlugs:   []string{"show/plain"},
+			expectedIssue:   "rejected",
+			expectedMessage: "contains unsafe markup",
+		},
+		{
+			name:            "Plain text",
+			policy:          config.HTML_POLICY_TEXT,
+			expectedSlugs:   []string{"show/unsafe", "show/plain"},
+			expectedIssue:   "modified",
+			expectedMessage: "converted to plain text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.HTMLPolicy = tt.policy
+			Configure(cfg)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?report=true", bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+
+			// only the unsafe description is reported, plain text is left alone
+			issues := response.Report.Modified
+			if tt.expectedIssue == "rejected" {
+				assert.Empty(t, response.Report.Modified)
+				issues = response.Report.Rejected
+			} else {
+				assert.Empty(t, response.Report.Rejected)
+			}
+			if assert.Len(t, issues, 1) {
+				assert.Equal(t, "show/unsafe", issues[0].Slug)
+				assert.Equal(t, "description", issues[0].Field)
+				assert.Contains(t, issues[0].Message, tt.expectedMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+
+	"stan.com/stantest/config"
+	"stan.com/stantest/imagecheck"
+	"stan.com/stantest/models"
+)
+
+// imageVerifier fetches show images, nil while the image check is off
+var imageVerifier *imagecheck.Verifier
+
+// newImageVerifier builds the verifier for the configured image check,
+// it shares its cache across requests
+func newImageVerifier(cfg *config.Config) *imagecheck.Verifier {
+	check := cfg.Validation.ImageCheck
+	if check.Mode == "" || check.Mode == config.IMAGE_CHECK_OFF {
+		return nil
+	}
+	client := imagecheck.NewClient(check.Timeout)
+	return imagecheck.New(client, imagecheck.Options{
+		Concurrency:  check.Concurrency,
+		Timeout:      check.Timeout,
+		CacheTTL:     check.CacheTTL,
+		ContentTypes: check.ContentTypes,
+		MinWidth:     check.MinWidth,
+		MinHeight:    check.MinHeight,
+	})
+}
+
+// verifyImages fetches the show images of the matched episodes. With the
+// drop mode episodes with an unusable image are rejected, otherwise they
+// are kept with a warning. Order is preserved.
+func verifyImages(ctx context.Context, verifier *imagecheck.Verifier, matched []episodeResult, mode string) ([]episodeResult, []models.ValidationIssue, []models.ValidationIssue) {
+	urls := make([]string, len(matched))
+	for i, result := range matched {
+		urls[i] = result.episode.Image.ShowImage
+	}
+	checked := verifier.CheckAll(ctx, urls)
+
+	var kept []episodeResult
+	var rejected, warnings []models.ValidationIssue
+	for _, result := range matched {
+		check, ok := checked[result.episode.Image.ShowImage]
+		// unchecked only when ctx ended, the caller handles that
+		if !ok || check.OK() {
+			kept = append(kept, result)
+			continue
+		}
+		issue := models.ValidationIssue{
+			Slug:    result.episode.Slug,
+			Field:   "image.showImage",
+			Message: "image.showImage is unusable: " + check.Problem,
+		}
+		if mode == config.IMAGE_CHECK_DROP {
+			rejected = append(rejected, issue)
+			continue
+		}
+		warnings = append(warnings, issue)
+		kept = append(kept, result)
+	}
+	return kept, rejected, warnings
+}
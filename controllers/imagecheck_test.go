@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/imagecheck"
+	"stan.com/stantest/models"
+)
+
+func TestImageCheck(t *testing.T) {
+	defer Configure(config.Default())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+	}))
+	defer server.Close()
+
+	body := fmt.Sprintf(`{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "%[1]s/ok.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/b", "title": "B", "image": {"showImage": "%[1]s/gone.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/c", "title": "C", "image": {"showImage": "%[1]s/ok.jpg"}}
+	]}`, server.URL)
+
+	tests := []struct {
+		name             string
+		mode             string
+		expectedSlugs    []string
+		expectedRejected int
+		expectedWarnings int
+	}{
+		{name: "Off", mode: config.IMAGE_CHECK_OFF, expectedSlugs: []string{"show/a", "show/b", "show/c"}},
+		{name: "Mark", mode: config.IMAGE_CHECK_MARK, expectedSlugs: []string{"show/a", "show/b", "show/c"}, expectedWarnings: 1},
+		{name: "Drop", mode: config.IMAGE_CHECK_DROP, expectedSlugs: []string{"show/a", "show/c"}, expectedRejected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			// the test server listens on loopback
+			cfg.Validation.URL.RejectIPLiterals = false
+			cfg.Validation.URL.RejectPrivate = false
+			cfg.Validation.ImageCheck.Mode = tt.mode
+			Configure(cfg)
+			if imageVerifier != nil {
+				imageVerifier = imagecheck.New(server.Client(), imagecheck.Options{Concurrency: 2})
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?report=true", bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			assert.Len(t, response.Report.Rejected, tt.expectedRejected)
+			assert.Len(t, response.Report.Warnings, tt.expectedWarnings)
+
+			for _, issue := range append(response.Report.Rejected, response.Report.Warnings...) {
+				assert.Equal(t, "show/b", issue.Slug)
+				assert.Equal(t, "image.showImage is unusable: unexpected status 404", issue.Message)
+			}
+		})
+	}
+}
+
+func TestImageCheckRefusesPrivateAddresses(t *testing.T) {
+	defer Configure(config.Default())
+
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Header().Set("Content-Type", "image/jpeg")
+	}))
+	defer server.Close()
+
+	// the default URL policy lets the loopback URL through, the fetcher must not
+	cfg := config.Default()
+	cfg.Validation.ImageCheck.Mode = config.IMAGE_CHECK_MARK
+	Configure(cfg)
+
+	body := fmt.Sprintf(`{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "%s/ok.jpg"}}
+	]}`, server.URL)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?report=true", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, DealwithEpisodes(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, fetched)
+
+	var response models.EpisodeResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	if assert.Len(t, response.Report.Warnings, 1) {
+		assert.Contains(t, response.Report.Warnings[0].Message, "refusing to connect to private address 127.0.0.1")
+	}
+}
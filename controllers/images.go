@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/models"
+)
+
+// selectImage returns the URL of the widest rendition with the wanted role
+// that fits maxWidth, falling back to the show image if none qualifies
+func selectImage(image models.Image, role string, maxWidth int) string {
+	if role == "" && maxWidth == 0 {
+		return image.ShowImage
+	}
+
+	best := -1
+	for i, rendition := range image.ImageSet {
+		if role != "" && rendition.Role != role {
+			continue
+		}
+		// unknown widths can't be proven to fit
+		if maxWidth > 0 && (rendition.Width <= 0 || rendition.Width > maxWidth) {
+			continue
+		}
+		if best < 0 || rendition.Width > image.ImageSet[best].Width {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return image.ShowImage
+	}
+	return image.ImageSet[best].URL
+}
+
+// validateImageSet checks every rendition of the image set
+func validateImageSet(image models.Image) error {
+	for i, rendition := range image.ImageSet {
+		field := fmt.Sprintf("image.imageSet[%d]", i)
+		if !slices.Contains(models.ImageRoles, rendition.Role) {
+			return echo.NewHTTPError(400, field+".role must be one of "+strings.Join(models.ImageRoles, ", "))
+		}
+		if rendition.Width < 0 || rendition.Height < 0 {
+			return echo.NewHTTPError(400, field+" dimensions must not be negative")
+		}
+		if err := checkURL(field+".url", rendition.URL, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+var testImage = models.Image{
+	ShowImage: "http://catchup.ninemsn.com.au/img/jump-in/shows/TheTaste1280.jpg",
+	ImageSet: []models.ImageRendition{
+		{Role: "thumbnail", Width: 320, Height: 180, URL: "http://img.example.com/taste-320.jpg", Format: "jpg"},
+		{Role: "thumbnail", Width: 640, Height: 360, URL: "http://img.example.com/taste-640.jpg", Format: "jpg"},
+		{Role: "thumbnail", Width: 1280, Height: 720, URL: "http://img.example.com/taste-1280.jpg", Format: "jpg"},
+		{Role: "poster", Width: 1000, Height: 1500, URL: "http://img.example.com/taste-poster.webp", Format: "webp"},
+	},
+}
+
+func TestSelectImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		maxWidth int
+		expected string
+	}{
+		{
+			name:     "No preference uses the show image",
+			expected: testImage.ShowImage,
+		},
+		{
+			name:     "Widest rendition of the role",
+			role:     "thumbnail",
+			expected: "http://img.example.com/taste-1280.jpg",
+		},
+		{
+			name:     "Widest rendition within max width",
+			role:     "thumbnail",
+			maxWidth: 640,
+			expected: "http://img.example.com/taste-640.jpg",
+		},
+		{
+			name:     "Max width without role",
+			maxWidth: 1000,
+			expected: "http://img.example.com/taste-poster.webp",
+		},
+		{
+			name:     "Missing role falls back to the show image",
+			role:     "logo",
+			expected: testImage.ShowImage,
+		},
+		{
+			name:     "Nothing small enough falls back to the show image",
+			role:     "poster",
+			maxWidth: 500,
+			expected: testImage.ShowImage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, selectImage(testImage, tt.role, tt.maxWidth))
+		})
+	}
+}
+
+func TestValidateImageSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		rendition models.ImageRendition
+		errMsg    string
+	}{
+		{
+			name:      "Valid rendition",
+			rendition: models.ImageRendition{Role: "hero", Width: 1920, Height: 1080, URL: "http://img.example.com/hero.jpg"},
+		},
+		{
+			name:      "Unknown role",
+			rendition: models.ImageRendition{Role: "banner", URL: "http://img.example.com/banner.jpg"},
+			errMsg:    "image.imageSet[0].role must be one of",
+		},
+		{
+			name:      "Invalid URL",
+			rendition: models.ImageRendition{Role: "logo", URL: "ftp://img.example.com/logo.png"},
+			errMsg:    "image.imageSet[0].url must be a valid URL",
+		},
+		{
+			name:      "Negative dimensions",
+			rendition: models.ImageRendition{Role: "logo", Width: -1, URL: "http://img.example.com/logo.png"},
+			errMsg:    "image.imageSet[0] dimensions must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateImageSet(models.Image{ImageSet: []models.ImageRendition{tt.rendition}})
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDealwithEpisodesImageRendition(t *testing.T) {
+	e := echo.New()
+	episode := models.Episode{DRM: true, EpisodeCount: 2, Slug: "show/thetaste", Title: "The Taste", Image: testImage}
+	body, _ := json.Marshal(models.EpisodeRequest{Payload: []models.Episode{episode}})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedImage  string
+	}{
+		{
+			name:           "Preferred rendition",
+			query:          "?imageRole=thumbnail&maxWidth=640",
+			expectedStatus: http.StatusOK,
+			expectedImage:  "http://img.example.com/taste-640.jpg",
+		},
+		{
+			name:           "Unknown role",
+			query:          "?imageRole=banner",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid max width",
+			query:          "?maxWidth=wide",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBuffer(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response models.EpisodeResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				assert.Len(t, response.Response, 1)
+				assert.Equal(t, tt.expectedImage, response.Response[0].Image)
+			}
+		})
+	}
+}
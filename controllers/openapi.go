@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/openapi"
+)
+
+// apiDocument describes the API, it never changes while serving
+var apiDocument = openapi.Build()
+
+// GetOpenAPI serves the OpenAPI document of the API
+func GetOpenAPI(c echo.Context) error {
+	return c.JSON(http.StatusOK, apiDocument)
+}
+
+// GetDocs serves the interactive API documentation
+func GetDocs(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, openapi.DocsPage)
+}
+
+// redocETag validates the embedded Redoc bundle, it changes with a release
+var redocETag = contentETag(openapi.RedocScript)
+
+// GetDocsScript serves the Redoc bundle the documentation page runs
+func GetDocsScript(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set("ETag", redocETag)
+	header.Set("Cache-Control", "public, max-age=86400")
+	if etagMatches(c, redocETag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, "text/javascript; charset=utf-8", openapi.RedocScript)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+	"stan.com/stantest/openapi"
+)
+
+// documentedParams are the parameters of an operation of the API document by name
+func documentedParams(t *testing.T, operationID string) map[string]openapi.Parameter {
+	for _, item := range apiDocument.Paths {
+		for _, operation := range item {
+			if operation.OperationID != operationID {
+				continue
+			}
+			params := map[string]openapi.Parameter{}
+			for _, param := range operation.Parameters {
+				params[param.Name] = param
+			}
+			return params
+		}
+	}
+	t.Fatalf("operation %s is not documented", operationID)
+	return nil
+}
+
+func paramNames(params map[string]openapi.Parameter) []string {
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	return names
+}
+
+// TestDocumentedParameters keeps the document in step with the parameters
+// the handlers read
+func TestDocumentedParameters(t *testing.T) {
+	filterEpisodes := documentedParams(t, "filterEpisodes")
+	filterShows := documentedParams(t, "filterShows")
+	assert.ElementsMatch(t, optionParams, paramNames(filterEpisodes))
+	assert.ElementsMatch(t, append([]string{"offset", "limit"}, optionParams...), paramNames(filterShows))
+	assert.ElementsMatch(t, showsParams, paramNames(documentedParams(t, "listShows")))
+	assert.ElementsMatch(t, suggestParams, paramNames(documentedParams(t, "suggestShows")))
+	assert.ElementsMatch(t, facetsParams, paramNames(documentedParams(t, "facetEpisodes")))
+	assert.ElementsMatch(t, facetsParams, paramNames(documentedParams(t, "facetShows")))
+
+	roles := make([]any, len(models.ImageRoles))
+	for i, role := range models.ImageRoles {
+		roles[i] = role
+	}
+	assert.Equal(t, roles, filterEpisodes["imageRole"].Schema.Enum)
+
+	// fields lists every optional field, spelled as each version does
+	for _, field := range optionalFields {
+		assert.Contains(t, strings.Split(strings.TrimPrefix(filterEpisodes["fields"].Description,
+			"Comma separated optional response fields: "), ", "), field)
+		assert.Contains(t, strings.Split(strings.TrimPrefix(filterShows["fields"].Description,
+			"Comma separated optional response fields: "), ", "), showField(field))
+	}
+}
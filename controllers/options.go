@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/models"
+	"stan.com/stantest/rules"
+	"stan.com/stantest/search"
+	"stan.com/stantest/vocab"
+)
+
+// requestOptions are the optional query parameters of an episode request
+type requestOptions struct {
+	// ImageRole picks a rendition from the image set for the image field
+	ImageRole string
+	// MaxWidth limits the rendition width, 0 means no limit
+	MaxWidth int
+	// Criteria replace defaultCriteria when set
+	Criteria []episodeFilter
+	// Filters narrow the matched episodes further
+	Filters []episodeFilter
+	// Query keeps the episodes matching a text search and ranks them by
+	// relevance, nil keeps the feed order
+	Query *search.Query
+	// Title keeps the episodes whose title or slug is close to it, allowing
+	// for typos, and ranks them by how close
+	Title string
+	// IndexKey identifies an episode list whose search index can be reused,
+	// empty builds one per request
+	IndexKey string
+	// Fields are the optional response fields to include
+	Fields map[string]bool
+	// Report adds the validation report to the response
+	Report bool
+	// Profile holds the declared rules to apply, nil applies none
+	Profile *rules.Profile
+	// Offset and Limit page through the matched episodes in v2, a zero
+	// limit returns them all
+	Offset int
+	Limit  int
+	// Aggregate receives each matched episode as it is collected, once per
+	// slug, instead of it being returned. Images are not checked and
+	// nothing is ranked.
+	Aggregate func(models.Episode)
+}
+
+// optionParams are the query parameters parseRequestOptions reads
+var optionParams = []string{
+	"imageRole", "maxWidth", "airsWithinDays", "hasUpcoming", "minSeasons", "latestSeasonDrm",
+	"country", "language", "genre", "q", "title", "report", "fields", "profile",
+}
+
+// optional response fields which can be requested with fields=
+const (
+	fieldNextEpisode   = "nextEpisode"
+	fieldSeasons       = "seasons"
+	fieldSeasonCount   = "seasonCount"
+	fieldPrimaryColour = "primaryColour"
+	fieldTextColour    = "textColour"
+	fieldCountry       = "country"
+	fieldLanguage      = "language"
+	fieldGenre         = "genre"
+)
+
+var optionalFields = []string{
+	fieldNextEpisode, fieldSeasons, fieldSeasonCount, fieldPrimaryColour, fieldTextColour,
+	fieldCountry, fieldLanguage, fieldGenre,
+}
+
+// showFieldNames are the v2 spellings of fields v1 spells differently
+var showFieldNames = map[string]string{
+	fieldPrimaryColour: "primaryColor",
+	fieldTextColour:    "textColor",
+}
+
+// showField is the name of a field, or of the field a path starts with, as
+// v2 spells it
+func showField(field string) string {
+	name, rest, _ := strings.Cut(field, ".")
+	if spelled, ok := showFieldNames[name]; ok {
+		if rest == "" {
+			return spelled
+		}
+		return spelled + "." + rest
+	}
+	return field
+}
+
+// parseRequestOptions reads and validates the query parameters, with
+// fields spelled as in v1
+func parseRequestOptions(c echo.Context) (requestOptions, error) {
+	return parseOptions(c, func(field string) string { return field })
+}
+
+// parseShowOptions reads and validates the query parameters of v2
+func parseShowOptions(c echo.Context) (requestOptions, error) {
+	return parseOptions(c, showField)
+}
+
+// parseOptions reads and validates the query parameters, spell gives the
+// name an optional field is requested by
+func parseOptions(c echo.Context, spell func(string) string) (requestOptions, error) {
+	var opts requestOptions
+
+	if role := c.QueryParam("imageRole"); role != "" {
+		if !slices.Contains(models.ImageRoles, role) {
+			return opts, fmt.Errorf("imageRole must be one of %v", models.ImageRoles)
+		}
+		opts.ImageRole = role
+	}
+
+	if maxWidth := c.QueryParam("maxWidth"); maxWidth != "" {
+		width, err := strconv.Atoi(maxWidth)
+		if err != nil || width <= 0 {
+			return opts, fmt.Errorf("maxWidth must be a positive integer")
+		}
+		opts.MaxWidth = width
+	}
+
+	if days := c.QueryParam("airsWithinDays"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("airsWithinDays must be a non-negative integer")
+		}
+		opts.Filters = append(opts.Filters, airsWithin(time.Duration(n)*24*time.Hour))
+	}
+
+	if upcoming := c.QueryParam("hasUpcoming"); upcoming != "" {
+		want, err := strconv.ParseBool(upcoming)
+		if err != nil {
+			return opts, fmt.Errorf("hasUpcoming must be true or false")
+		}
+		opts.Filters = append(opts.Filters, hasUpcoming(want))
+	}
+
+	if seasons := c.QueryParam("minSeasons"); seasons != "" {
+		n, err := strconv.Atoi(seasons)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("minSeasons must be a non-negative integer")
+		}
+		opts.Filters = append(opts.Filters, minSeasons(n))
+	}
+
+	if drm := c.QueryParam("latestSeasonDrm"); drm != "" {
+		want, err := strconv.ParseBool(drm)
+		if err != nil {
+			return opts, fmt.Errorf("latestSeasonDrm must be true or false")
+		}
+		opts.Filters = append(opts.Filters, latestSeasonDRM(want))
+	}
+
+	if country := c.QueryParam("country"); country != "" {
+		code, ok := vocab.Country(country)
+		if !ok {
+			return opts, fmt.Errorf("country %q is not a known ISO 3166 country", country)
+		}
+		opts.Filters = append(opts.Filters, countryIs(code))
+	}
+
+	if lang := c.QueryParam("language"); lang != "" {
+		tag, ok := vocab.Language(lang)
+		if !ok {
+			return opts, fmt.Errorf("language %q is not a known BCP 47 language", lang)
+		}
+		opts.Filters = append(opts.Filters, languageIs(tag))
+	}
+
+	if genre := c.QueryParam("genre"); genre != "" {
+		code, ok := vocab.Genres.Normalize(genre)
+		if !ok {
+			return opts, fmt.Errorf("genre %q is not in the genre taxonomy", genre)
+		}
+		opts.Filters = append(opts.Filters, genreIs(code))
+	}
+
+	if q := c.QueryParam("q"); q != "" {
+		query, err := search.ParseQuery(q)
+		if err != nil {
+			return opts, fmt.Errorf("q %s", err.Error())
+		}
+		opts.Query = query
+	}
+
+	if title := c.QueryParam("title"); title != "" {
+		if len(search.Tokenize(title)) == 0 {
+			return opts, fmt.Errorf("title must contain a word")
+		}
+		opts.Title = title
+	}
+
+	if report := c.QueryParam("report"); report != "" {
+		want, err := strconv.ParseBool(report)
+		if err != nil {
+			return opts, fmt.Errorf("report must be true or false")
+		}
+		opts.Report = want
+	}
+
+	if fields := c.QueryParam("fields"); fields != "" {
+		spelled := map[string]string{}
+		names := make([]string, len(optionalFields))
+		for i, field := range optionalFields {
+			names[i] = spell(field)
+			spelled[names[i]] = field
+		}
+		opts.Fields = map[string]bool{}
+		for _, name := range strings.Split(fields, ",") {
+			field, ok := spelled[strings.TrimSpace(name)]
+			if !ok {
+				return opts, fmt.Errorf("fields must be a comma separated list of %v", names)
+			}
+			opts.Fields[field] = true
+		}
+	}
+
+	profile := c.QueryParam("profile")
+	if profile == "" {
+		profile = settings.Validation.RulesProfile
+	}
+	if profile != "" {
+		compiled, ok := rules.Profiles.Profile(profile)
+		if !ok {
+			return opts, fmt.Errorf("profile must be one of %v", rules.Profiles.Names())
+		}
+		opts.Profile = compiled
+	}
+
+	return opts, nil
+}
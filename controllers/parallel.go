@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"stan.com/stantest/models"
+)
+
+// parallelBatch is how many episodes a worker claims at a time, small
+// enough to even out slow episodes and large enough to keep claiming cheap
+const parallelBatch = 64
+
+// workerPanic is a panic raised by evaluate on a worker, kept with the
+// worker's stack, which is lost once it is raised again elsewhere
+type workerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *workerPanic) Error() string {
+	return fmt.Sprintf("episode worker: %v\n%s", p.value, p.stack)
+}
+
+// episodeWorkers is the number of workers to process n episodes with,
+// 1 means they are processed sequentially
+func episodeWorkers(n int) int {
+	if n < settings.Processing.ParallelMinEpisodes {
+		return 1
+	}
+	workers := settings.Processing.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// a worker without a batch to claim is wasted
+	return max(1, min(workers, (n+parallelBatch-1)/parallelBatch))
+}
+
+// evaluateParallel runs evaluate over the episodes on a pool of workers.
+// Results are stored by index, so the caller reads them in payload order
+// whichever worker finished first. Workers stop claiming batches once ctx
+// is done. A panic in a worker is raised again on the calling goroutine as
+// a *workerPanic, where the recover middleware can answer it.
+func evaluateParallel(ctx context.Context, payload []models.Episode, workers int, evaluate func(int, models.Episode) episodeResult) ([]episodeResult, error) {
+	results := make([]episodeResult, len(payload))
+	var (
+		next     atomic.Int64
+		wg       sync.WaitGroup
+		panicked atomic.Value
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panicked.CompareAndSwap(nil, &workerPanic{value: r, stack: debug.Stack()})
+				}
+			}()
+			for ctx.Err() == nil {
+				start := int(next.Add(parallelBatch)) - parallelBatch
+				if start >= len(payload) {
+					return
+				}
+				end := min(start+parallelBatch, len(payload))
+				for i := start; i < end; i++ {
+					results[i] = evaluate(i, payload[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r := panicked.Load(); r != nil {
+		panic(r)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/logging"
+	"stan.com/stantest/models"
+)
+
+// largePayload builds n shows, most valid and matching, some filtered out
+// and some rejected, so every path of the loop is taken
+func largePayload(n int) []models.Episode {
+	genres := []string{"Drama", "Comedy", "Reality", "Documentary"}
+	payload := make([]models.Episode, n)
+	for i := range payload {
+		episode := models.Episode{
+			Country:      "AU",
+			Description:  fmt.Sprintf("The <b>story</b> of show %d and its family", i),
+			DRM:          i%7 != 0,
+			EpisodeCount: i % 13,
+			Genre:        genres[i%len(genres)],
+			Image:        models.Image{ShowImage: fmt.Sprintf("http://img.example.com/%d.jpg", i)},
+			Language:     "English",
+			PrimaryColor: "#df0000",
+			Slug:         fmt.Sprintf("show/show-%d", i),
+			Title:        fmt.Sprintf("Show %d", i),
+			TVChannel:    "Stan",
+		}
+		switch i % 11 {
+		case 3:
+			episode.Title = ""
+		case 5:
+			episode.Image.ShowImage = "not a url"
+		case 8:
+			episode.PrimaryColor = "red"
+		}
+		payload[i] = episode
+	}
+	return payload
+}
+
+func TestEpisodeWorkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		minSize  int
+		episodes int
+		expected int
+	}{
+		{"Small payload is sequential", 4, 1000, 999, 1},
+		{"Large payload", 4, 1000, 1000, 4},
+		{"One worker is sequential", 1, 0, 100000, 1},
+		{"Default is one per CPU", 0, 0, 100000, runtime.GOMAXPROCS(0)},
+		{"No more workers than batches", 8, 0, 3 * parallelBatch, 3},
+		{"Partial batch", 8, 0, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Processing.Workers = tt.workers
+			cfg.Processing.ParallelMinEpisodes = tt.minSize
+			Configure(cfg)
+			defer Configure(config.Default())
+
+			assert.Equal(t, tt.expected, episodeWorkers(tt.episodes))
+		})
+	}
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	body, err := json.Marshal(models.EpisodeRequest{Payload: largePayload(5000)})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Filtered", "/api/v1/episodes"},
+		{"With report", "/api/v1/episodes?report=true"},
+		{"Searched", "/api/v1/episodes?q=show+family&report=true"},
+		{"Filtered by genre", "/api/v1/episodes?genre=comedy&minEpisodes=5"},
+	}
+
+	run := func(path string, workers int) *httptest.ResponseRecorder {
+		cfg := config.Default()
+		cfg.Processing.Workers = workers
+		cfg.Processing.ParallelMinEpisodes = 0
+		Configure(cfg)
+		defer Configure(config.Default())
+		return post(DealwithEpisodes, path, string(body), nil)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequential := run(tt.path, 1)
+			assert.Equal(t, http.StatusOK, sequential.Code)
+			for _, workers := range []int{2, 3, 8} {
+				parallel := run(tt.path, workers)
+				assert.Equal(t, http.StatusOK, parallel.Code)
+				assert.JSONEq(t, sequential.Body.String(), parallel.Body.String(), "%d workers", workers)
+			}
+		})
+	}
+}
+
+func TestParallelDeadline(t *testing.T) {
+	cfg := config.Default()
+	cfg.Processing.Workers = 4
+	cfg.Processing.ParallelMinEpisodes = 0
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	body, err := json.Marshal(models.EpisodeRequest{Payload: largePayload(1000)})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, DealwithEpisodes(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestEvaluateParallelPanics(t *testing.T) {
+	payload := largePayload(500)
+	defer func() {
+		r := recover()
+		if assert.IsType(t, &workerPanic{}, r) {
+			raised := r.(*workerPanic)
+			assert.Equal(t, "boom", raised.value)
+			// the stack is the worker's, down to where it panicked
+			assert.Contains(t, string(raised.stack), "TestEvaluateParallelPanics.func")
+			assert.True(t, strings.HasPrefix(raised.Error(), "episode worker: boom\n"))
+		}
+	}()
+	_, _ = evaluateParallel(context.Background(), payload, 4, func(i int, episode models.Episode) episodeResult {
+		if i == 300 {
+			panic("boom")
+		}
+		return episodeResult{}
+	})
+	t.Error("the panic was not raised again")
+}
+
+// BenchmarkRunEpisodes compares the sequential loop, workers=1, with the
+// worker pool on a large payload, logging at the default level
+func BenchmarkRunEpisodes(b *testing.B) {
+	payload := largePayload(50000)
+	counts := []int{1, 2, 4}
+	if cpus := runtime.GOMAXPROCS(0); !slices.Contains(counts, cpus) {
+		counts = append(counts, cpus)
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := config.Default()
+			cfg.Processing.Workers = workers
+			cfg.Processing.ParallelMinEpisodes = 0
+			Configure(cfg)
+			defer Configure(config.Default())
+
+			e := echo.New()
+			e.Logger.SetOutput(io.Discard)
+			e.Logger.SetHeader(logging.Header())
+			e.Logger.SetLevel(cfg.LogLevel)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := runEpisodes(c, payload, requestOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"stan.com/stantest/models"
+	"stan.com/stantest/rules"
+)
+
+// ruleViolations rejects an episode which broke declared rules,
+// every broken rule is reported on its own
+type ruleViolations struct {
+	issues []models.ValidationIssue
+}
+
+func (e *ruleViolations) Error() string {
+	messages := make([]string, len(e.issues))
+	for i, issue := range e.issues {
+		messages[i] = issue.Rule + ": " + issue.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// applyRules evaluates the rules of the selected profile. Broken warning
+// rules are returned, broken error rules reject the episode.
+func applyRules(episode models.Episode, profile *rules.Profile) ([]models.ValidationIssue, error) {
+	if profile == nil {
+		return nil, nil
+	}
+	violations, err := profile.Check(episode)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []models.ValidationIssue
+	var rejected ruleViolations
+	for _, violation := range violations {
+		issue := models.ValidationIssue{Slug: episode.Slug, Field: violation.Field, Rule: violation.RuleID, Message: violation.Message}
+		if violation.Severity == rules.SeverityWarning {
+			warnings = append(warnings, issue)
+			continue
+		}
+		rejected.issues = append(rejected.issues, issue)
+	}
+	if len(rejected.issues) > 0 {
+		return nil, &rejected
+	}
+	return warnings, nil
+}
+
+// fieldError rejects an episode for the value of one field
+type fieldError struct {
+	field   string
+	message string
+}
+
+func (e *fieldError) Error() string {
+	return e.field + " " + e.message
+}
+
+// rejectionIssues turns the error which rejected an episode into report issues
+func rejectionIssues(slug string, err error) []models.ValidationIssue {
+	var violations *ruleViolations
+	if errors.As(err, &violations) {
+		return violations.issues
+	}
+	var invalid *fieldError
+	if errors.As(err, &invalid) {
+		return []models.ValidationIssue{{Slug: slug, Field: invalid.field, Message: invalid.message}}
+	}
+	return []models.ValidationIssue{{Slug: slug, Message: errorMessage(err)}}
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+	"stan.com/stantest/rules"
+)
+
+func TestDeclaredRules(t *testing.T) {
+	maxLength := 20.0
+	set, err := rules.Compile(rules.File{Profiles: map[string]rules.ProfileSpec{
+		"kids": {Rules: []rules.RuleSpec{
+			{ID: "KIDS-001", Type: rules.TypeRequired, Field: "genre"},
+			{ID: "KIDS-002", Type: rules.TypeRequired, Field: "description"},
+			{ID: "KIDS-003", Type: rules.TypeLength, Field: "description", Max: &maxLength, Severity: rules.SeverityWarning},
+		}},
+	}})
+	assert.NoError(t, err)
+	defaultProfiles := rules.Profiles
+	rules.Profiles = set
+	defer func() { rules.Profiles = defaultProfiles }()
+	defer Configure(config.Default())
+
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/b", "title": "B", "genre": "Animation",
+			"description": "A long running animated series for the whole family",
+			"image": {"showImage": "http://img.example.com/b.jpg"}}
+	]}`
+
+	tests := []struct {
+		name             string
+		defaultProfile   string
+		query            string
+		expectedCode     int
+		expectedSlugs    []string
+		expectedRejected []models.ValidationIssue
+		expectedWarnings int
+	}{
+		{
+			name:          "No profile",
+			query:         "?report=true",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/a", "show/b"},
+		},
+		{
+			name:          "Kids profile",
+			query:         "?report=true&profile=kids",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/b"},
+			expectedRejected: []models.ValidationIssue{
+				{Slug: "show/a", Field: "genre", Rule: "KIDS-001", Message: "genre is required"},
+				{Slug: "show/a", Field: "description", Rule: "KIDS-002", Message: "description is required"},
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "Configured default profile",
+			defaultProfile:   "kids",
+			query:            "?report=true",
+			expectedCode:     http.StatusOK,
+			expectedSlugs:    []string{"show/b"},
+			expectedRejected: []models.ValidationIssue{{Slug: "show/a", Field: "genre", Rule: "KIDS-001", Message: "genre is required"}, {Slug: "show/a", Field: "description", Rule: "KIDS-002", Message: "description is required"}},
+			expectedWarnings: 1,
+		},
+		{
+			name:         "Unknown profile",
+			query:        "?profile=teens",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.RulesProfile = tt.defaultProfile
+			Configure(cfg)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode != http.StatusOK {
+				var errorResponse map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+				assert.Equal(t, "Invalid query parameter: profile must be one of [kids]", errorResponse["error"])
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			if tt.expectedRejected == nil {
+				assert.Empty(t, response.Report.Rejected)
+			} else {
+				assert.Equal(t, tt.expectedRejected, response.Report.Rejected)
+			}
+			assert.Len(t, response.Report.Warnings, tt.expectedWarnings)
+			for _, warning := range response.Report.Warnings {
+				assert.Equal(t, "KIDS-003", warning.Rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/models"
+	"stan.com/stantest/schema"
+)
+
+// episodeSchema is the published schema request bodies are checked against
+var episodeSchema = mustParseSchema(models.EpisodeSchema)
+
+// showsSchema is the published schema v2 request bodies are checked against
+var showsSchema = mustParseSchema(models.ShowsSchema)
+
+func mustParseSchema(raw []byte) *schema.Schema {
+	parsed, err := schema.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// GetSchema serves the JSON Schema of the episode request and response
+func GetSchema(c echo.Context) error {
+	return c.Blob(http.StatusOK, "application/schema+json", models.EpisodeSchema)
+}
+
+// GetShowsSchema serves the JSON Schema of the v2 request and response
+func GetShowsSchema(c echo.Context) error {
+	return c.Blob(http.StatusOK, "application/schema+json", models.ShowsSchema)
+}
+
+// schemaErrorBody is the error response for a body breaking the schema,
+// every violation is listed with its JSON pointer
+func schemaErrorBody(c echo.Context, violations []schema.Violation) map[string]any {
+	body := map[string]any{"violations": violations}
+	for key, value := range errorBody(c, "Could not decode request: body does not match the schema") {
+		body[key] = value
+	}
+	return body
+}
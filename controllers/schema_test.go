@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestGetSchema(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/schema", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, GetSchema(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/schema+json", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, models.EpisodeSchema, rec.Body.Bytes())
+}
+
+func TestSchemaValidation(t *testing.T) {
+	defer Configure(config.Default())
+
+	body := `{"payload": [
+		{"drm": "yes", "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1.5, "slug": "show/b", "title": "B",
+			"image": {"showImage": "http://img.example.com/b.jpg", "imageSet": [{"role": "banner", "url": "http://img.example.com/b.jpg"}]}}
+	]}`
+
+	tests := []struct {
+		name               string
+		enabled            bool
+		body               string
+		expectedCode       int
+		expectedViolations []map[string]string
+	}{
+		{
+			name:         "Disabled",
+			body:         `{"payload": []}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Valid body",
+			enabled:      true,
+			body:         `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}]}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Violations are listed with pointers",
+			enabled:      true,
+			body:         body,
+			expectedCode: http.StatusBadRequest,
+			expectedViolations: []map[string]string{
+				{"pointer": "/payload/0/drm", "message": "expected boolean, got string"},
+				{"pointer": "/payload/1/episodeCount", "message": "expected integer, got number"},
+				{"pointer": "/payload/1/image/imageSet/0/role", "message": "must be one of poster, hero, thumbnail, logo"},
+			},
+		},
+		{
+			name:               "Missing payload",
+			enabled:            true,
+			body:               `{"payload": null}`,
+			expectedCode:       http.StatusBadRequest,
+			expectedViolations: []map[string]string{{"pointer": "/payload", "message": "expected array, got null"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.Schema = tt.enabled
+			Configure(cfg)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewBufferString(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedViolations == nil {
+				return
+			}
+
+			var errorResponse struct {
+				Error      string              `json:"error"`
+				Violations []map[string]string `json:"violations"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+			assert.Equal(t, "Could not decode request: body does not match the schema", errorResponse.Error)
+			assert.Equal(t, tt.expectedViolations, errorResponse.Violations)
+		})
+	}
+}
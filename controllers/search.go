@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+
+	"stan.com/stantest/models"
+	"stan.com/stantest/sanitize"
+	"stan.com/stantest/search"
+)
+
+// searchFields are the episode fields a text search looks at, titles weigh more
+var searchFields = []search.Field{
+	{Name: "title", Weight: 2},
+	{Name: "description", Weight: 1},
+}
+
+// indexCache keeps the index of the last stored catalogue queried, so
+// it's built once per upload rather than per request
+type indexCache[T any] struct {
+	mu    sync.Mutex
+	key   string
+	index T
+}
+
+// get returns the cached index when the key matches and builds it otherwise,
+// an empty key is never cached
+func (c *indexCache[T]) get(key string, build func() T) T {
+	if key == "" {
+		return build()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != key {
+		c.index = build()
+		c.key = key
+	}
+	return c.index
+}
+
+var (
+	searchIndexes  indexCache[*search.Index]
+	fuzzyIndexes   indexCache[*search.FuzzyIndex]
+	suggestIndexes indexCache[*suggestIndex]
+)
+
+// indexEpisodes builds a search index over the episodes, document numbers
+// are their positions. Descriptions are indexed by their text, so markup
+// can neither match nor skew the scores.
+func indexEpisodes(episodes []models.Episode) *search.Index {
+	index := search.NewIndex(searchFields...)
+	for _, episode := range episodes {
+		index.Add(episode.Title, sanitize.Text(episode.Description))
+	}
+	return index
+}
+
+// fuzzyIndexEpisodes indexes the episodes by title and slug for typo
+// tolerant lookups, document numbers are their positions
+func fuzzyIndexEpisodes(episodes []models.Episode) *search.FuzzyIndex {
+	index := search.NewFuzzyIndex()
+	for _, episode := range episodes {
+		index.Add(episode.Title, episode.Slug)
+	}
+	return index
+}
+
+// fuzzyOptions are the configured bounds of a title match
+func fuzzyOptions() search.FuzzyOptions {
+	return search.FuzzyOptions{
+		MaxDistance:   settings.Search.FuzzyMaxDistance,
+		MinSimilarity: settings.Search.FuzzyMinSimilarity,
+	}
+}
+
+// searchEpisodes maps the positions of the episodes matching the text
+// search and the title to their relevance, the sum of both scores
+func searchEpisodes(episodes []models.Episode, opts requestOptions) map[int]float64 {
+	var scores map[int]float64
+	if opts.Query != nil {
+		index := searchIndexes.get(opts.IndexKey, func() *search.Index { return indexEpisodes(episodes) })
+		scores = map[int]float64{}
+		for _, hit := range index.Search(opts.Query) {
+			scores[hit.Doc] = hit.Score
+		}
+	}
+	if opts.Title != "" {
+		index := fuzzyIndexes.get(opts.IndexKey, func() *search.FuzzyIndex { return fuzzyIndexEpisodes(episodes) })
+		titles := map[int]float64{}
+		for _, match := range index.Match(opts.Title, fuzzyOptions()) {
+			titles[match.Doc] = match.Score
+		}
+		if scores == nil {
+			scores = titles
+		} else {
+			for doc := range scores {
+				if score, ok := titles[doc]; ok {
+					scores[doc] += score
+				} else {
+					delete(scores, doc)
+				}
+			}
+		}
+	}
+	return scores
+}
+
+// roundScore keeps scores readable and stable across platforms
+func roundScore(score float64) *float64 {
+	rounded := math.Round(score*1e4) / 1e4
+	return &rounded
+}
+
+// rankResults orders results by relevance, equally relevant ones keep
+// the feed order
+func rankResults(results []episodeResult) {
+	slices.SortStableFunc(results, func(a, b episodeResult) int {
+		return cmp.Compare(b.score, a.score)
+	})
+}
+
+// suggestIndex is a fuzzy index over the titles worth suggesting, the
+// shows a query with default criteria could return
+type suggestIndex struct {
+	index    *search.FuzzyIndex
+	episodes []models.Episode
+}
+
+func newSuggestIndex(episodes []models.Episode) *suggestIndex {
+	s := &suggestIndex{index: search.NewFuzzyIndex()}
+	at := now()
+	seen := map[string]bool{}
+	for _, episode := range episodes {
+		if episode.Title == "" || seen[episode.Slug] || !matchesFilters(episode, defaultCriteria, at) {
+			continue
+		}
+		seen[episode.Slug] = true
+		s.index.Add(episode.Title, episode.Slug)
+		s.episodes = append(s.episodes, episode)
+	}
+	return s
+}
+
+// suggest returns titles close to what was typed so far
+func (s *suggestIndex) suggest(text string, limit int) []models.Suggestion {
+	opts := fuzzyOptions()
+	opts.Prefix = true
+	opts.Limit = limit
+
+	suggestions := []models.Suggestion{}
+	for _, match := range s.index.Match(text, opts) {
+		episode := s.episodes[match.Doc]
+		suggestions = append(suggestions, models.Suggestion{
+			Title:    episode.Title,
+			Slug:     episode.Slug,
+			Score:    *roundScore(match.Score),
+			Distance: match.Distance,
+		})
+	}
+	return suggestions
+}
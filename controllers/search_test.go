@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+func TestSearchEpisodes(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 3, "slug": "show/taste", "title": "The Taste",
+			"description": "Chefs compete in a culinary contest", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 8, "slug": "show/legout", "title": "Le Goût",
+			"description": "A culinary tour of France", "image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": false, "episodeCount": 5, "slug": "show/culinary", "title": "Culinary Class",
+			"description": "Cooking lessons", "image": {"showImage": "http://img.example.com/c.jpg"}},
+		{"drm": true, "episodeCount": 10, "slug": "show/matchday", "title": "Matchday",
+			"description": "Behind the scenes with a football team", "image": {"showImage": "http://img.example.com/d.jpg"}},
+		{"drm": true, "episodeCount": 2, "slug": "show/teamfootball", "title": "Team Spirit",
+			"description": "The team plays football every week", "image": {"showImage": "http://img.example.com/e.jpg"}},
+		{"drm": true, "episodeCount": 4, "slug": "show/kitchen", "title": "Kitchen",
+			"description": "<span class=\"spoiler\">Recipes</span> &amp; tips", "image": {"showImage": "http://img.example.com/f.jpg"}}
+	]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSlugs  []string
+	}{
+		{
+			name:           "Combined with the DRM criteria",
+			query:          "?q=culinary",
+			expectedStatus: http.StatusOK,
+			// the shorter description makes the word count for more
+			expectedSlugs: []string{"show/legout", "show/taste"},
+		},
+		{
+			name:           "Diacritics are ignored",
+			query:          "?q=gout",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/legout"},
+		},
+		{
+			name:           "Ranked by relevance",
+			query:          "?q=team",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/teamfootball", "show/matchday"},
+		},
+		{
+			name:           "Phrase",
+			query:          "?q=%22football+team%22",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/matchday"},
+		},
+		{
+			name:           "Combined with other filters",
+			query:          "?q=football&minSeasons=1",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  nil,
+		},
+		{
+			name:           "Description text",
+			query:          "?q=recipes",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/kitchen"},
+		},
+		{
+			name:           "Tags aren't searched",
+			query:          "?q=span",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  nil,
+		},
+		{
+			name:           "Attributes aren't searched",
+			query:          "?q=spoiler",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  nil,
+		},
+		{
+			name:           "Unterminated phrase",
+			query:          "?q=%22football",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+		})
+	}
+}
+
+func TestIndexCache(t *testing.T) {
+	var cache indexCache[*int]
+	builds := 0
+	build := func() *int { builds++; n := builds; return &n }
+
+	first := cache.get("v1", build)
+	assert.Same(t, first, cache.get("v1", build))
+	assert.NotSame(t, first, cache.get("v2", build))
+	cache.get("", build)
+	cache.get("", build)
+	assert.Equal(t, 4, builds)
+}
+
+func TestTitleSearch(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 24, "slug": "show/thunderbirds", "title": "Thunderbirds",
+			"description": "Puppets to the rescue", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 12, "slug": "show/thunderbirdsarego", "title": "Thunderbirds Are Go",
+			"description": "International Rescue returns", "image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": true, "episodeCount": 3, "slug": "show/thetaste", "title": "The Taste",
+			"description": "Chefs compete", "image": {"showImage": "http://img.example.com/c.jpg"}}
+	]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSlugs  []string
+		expectedScores []float64
+	}{
+		{
+			name:           "Typo in the title",
+			query:          "?title=thunderbrds",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/thunderbirds", "show/thunderbirdsarego"},
+			expectedScores: []float64{0.9091, 0.9091},
+		},
+		{
+			name:           "Exact title ranks first",
+			query:          "?title=thunderbirds+are+go",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/thunderbirdsarego"},
+			expectedScores: []float64{1},
+		},
+		{
+			name:           "Slug",
+			query:          "?title=thetaste",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/thetaste"},
+			expectedScores: []float64{1},
+		},
+		{
+			name:           "Combined with a text search",
+			query:          "?title=thunderbirds&q=international",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/thunderbirdsarego"},
+		},
+		{
+			name:           "Blank title",
+			query:          "?title=%3F",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			var scores []float64
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+				if assert.NotNil(t, item.Score) {
+					scores = append(scores, *item.Score)
+				}
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			if tt.expectedScores != nil {
+				assert.Equal(t, tt.expectedScores, scores)
+			}
+		})
+	}
+}
+
+func TestScoresOnlyWithSearches(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, DealwithEpisodes(e.NewContext(req, rec)))
+	assert.NotContains(t, rec.Body.String(), "score")
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/models"
+)
+
+// validateSeasons checks each season and that the seasons agree with the show,
+// seasons without a slug are left to dropSeasonsWithoutSlug
+func validateSeasons(episode models.Episode) error {
+	numbers := map[int]bool{}
+	total := 0
+
+	for i, season := range episode.Seasons {
+		field := fmt.Sprintf("seasons[%d]", i)
+		if season.Slug == "" {
+			continue
+		}
+		if season.Number < 0 {
+			return echo.NewHTTPError(400, field+".number must not be negative")
+		}
+		if season.Number > 0 {
+			if numbers[season.Number] {
+				return echo.NewHTTPError(400, fmt.Sprintf("%s.number %d is used more than once", field, season.Number))
+			}
+			numbers[season.Number] = true
+		}
+		if season.EpisodeCount < 0 {
+			return echo.NewHTTPError(400, field+".episodeCount must not be negative")
+		}
+		if season.Year != 0 && (season.Year < 1900 || season.Year > 2100) {
+			return echo.NewHTTPError(400, field+".year is out of range")
+		}
+		if season.AvailableFrom.Valid() && season.AvailableTo.Valid() && season.AvailableTo.Before(season.AvailableFrom.Time) {
+			return echo.NewHTTPError(400, field+".availableTo is before availableFrom")
+		}
+		total += season.EpisodeCount
+	}
+
+	// seasons may be listed partially, but never hold more episodes than the show
+	if total > episode.EpisodeCount {
+		return echo.NewHTTPError(400, fmt.Sprintf("seasons hold %d episodes but episodeCount is %d", total, episode.EpisodeCount))
+	}
+	return nil
+}
+
+// dropSeasonsWithoutSlug removes the seasons we can't link to, the show is
+// kept with the rest of its seasons and each removal is reported
+func dropSeasonsWithoutSlug(episode *models.Episode) []models.ValidationIssue {
+	var modified []models.ValidationIssue
+	var seasons []models.Season
+	for i, season := range episode.Seasons {
+		if season.Slug != "" {
+			seasons = append(seasons, season)
+			continue
+		}
+		modified = append(modified, models.ValidationIssue{
+			Slug:    episode.Slug,
+			Field:   fmt.Sprintf("seasons[%d].slug", i),
+			Message: "removed season without a slug",
+		})
+	}
+	// the payload's slice is shared, so it is only replaced
+	if modified != nil {
+		episode.Seasons = seasons
+	}
+	return modified
+}
+
+// listedSeasons are the seasons kept in the response, those with a slug
+func listedSeasons(episode models.Episode) []models.Season {
+	var seasons []models.Season
+	for _, season := range episode.Seasons {
+		if season.Slug != "" {
+			seasons = append(seasons, season)
+		}
+	}
+	return seasons
+}
+
+// latestSeason is the highest numbered season, or the last listed when unnumbered
+func latestSeason(episode models.Episode) (models.Season, bool) {
+	seasons := listedSeasons(episode)
+	if len(seasons) == 0 {
+		return models.Season{}, false
+	}
+	latest := seasons[len(seasons)-1]
+	for _, season := range seasons {
+		if season.Number > latest.Number {
+			latest = season
+		}
+	}
+	return latest, true
+}
+
+// minSeasons keeps shows with at least n seasons
+func minSeasons(n int) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		return len(listedSeasons(episode)) >= n
+	}
+}
+
+// latestSeasonDRM keeps shows whose latest season has (or lacks) DRM
+func latestSeasonDRM(want bool) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		latest, ok := latestSeason(episode)
+		return ok && latest.DRM == want
+	}
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+func TestValidateSeasons(t *testing.T) {
+	tests := []struct {
+		name         string
+		episodeCount int
+		seasons      []models.Season
+		errMsg       string
+	}{
+		{
+			name:         "Slug only seasons",
+			episodeCount: 24,
+			seasons:      []models.Season{{Slug: "show/thunderbirds/season/1"}, {Slug: "show/thunderbirds/season/3"}},
+		},
+		{
+			name:         "Consistent episode counts",
+			episodeCount: 24,
+			seasons: []models.Season{
+				{Slug: "show/thunderbirds/season/1", Number: 1, EpisodeCount: 12, Year: 1965},
+				{Slug: "show/thunderbirds/season/2", Number: 2, EpisodeCount: 12, Year: 1966},
+			},
+		},
+		{
+			name:         "Seasons hold more episodes than the show",
+			episodeCount: 10,
+			seasons: []models.Season{
+				{Slug: "show/thunderbirds/season/1", Number: 1, EpisodeCount: 12},
+			},
+			errMsg: "seasons hold 12 episodes but episodeCount is 10",
+		},
+		{
+			name:         "Duplicate season number",
+			episodeCount: 24,
+			seasons: []models.Season{
+				{Slug: "show/thunderbirds/season/1", Number: 1},
+				{Slug: "show/thunderbirds/season/1b", Number: 1},
+			},
+			errMsg: "seasons[1].number 1 is used more than once",
+		},
+		{
+			name:         "Missing season slug is left to processing",
+			episodeCount: 24,
+			seasons:      []models.Season{{Number: 1}},
+		},
+		{
+			name:         "Seasons without a slug are not counted",
+			episodeCount: 12,
+			seasons: []models.Season{
+				{Number: 1, EpisodeCount: 12},
+				{Slug: "show/thunderbirds/season/1", Number: 1, EpisodeCount: 12},
+			},
+		},
+		{
+			name:         "Availability window ends before it starts",
+			episodeCount: 24,
+			seasons: []models.Season{{
+				Slug:          "show/thunderbirds/season/1",
+				AvailableFrom: feedTime("2024-03-01"),
+				AvailableTo:   feedTime("2024-02-01"),
+			}},
+			errMsg: "seasons[0].availableTo is before availableFrom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSeasons(models.Episode{EpisodeCount: tt.episodeCount, Seasons: tt.seasons})
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func feedTime(raw string) models.FeedTime {
+	return models.FeedTime{Raw: raw}.In(time.UTC)
+}
+
+func TestSeasonFiltersAndProjection(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 24, "slug": "show/thunderbirds", "title": "Thunderbirds",
+			"image": {"showImage": "http://img.example.com/a.jpg"},
+			"seasons": [{"slug": "show/thunderbirds/season/2", "number": 2, "drm": true},
+				{"slug": "show/thunderbirds/season/1", "number": 1, "drm": false}]},
+		{"drm": true, "episodeCount": 3, "slug": "show/16kidsandcounting", "title": "16 Kids and Counting",
+			"image": {"showImage": "http://img.example.com/b.jpg"},
+			"seasons": [{"slug": "show/16kidsandcounting/season/1", "number": 1, "drm": false}]},
+		{"drm": true, "episodeCount": 2, "slug": "show/thetaste", "title": "The Taste",
+			"image": {"showImage": "http://img.example.com/c.jpg"}}
+	]}`
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedSlugs []string
+	}{
+		{
+			name:          "At least two seasons",
+			query:         "?minSeasons=2",
+			expectedSlugs: []string{"show/thunderbirds"},
+		},
+		{
+			name:          "Latest season has DRM",
+			query:         "?latestSeasonDrm=true",
+			expectedSlugs: []string{"show/thunderbirds"},
+		},
+		{
+			name:          "Latest season without DRM",
+			query:         "?latestSeasonDrm=false",
+			expectedSlugs: []string{"show/16kidsandcounting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+		})
+	}
+
+	t.Run("Season count projection", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?fields=seasonCount", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		assert.NoError(t, DealwithEpisodes(c))
+		var response models.EpisodeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Len(t, response.Response, 3)
+		for i, expected := range []int{2, 1, 0} {
+			assert.NotNil(t, response.Response[i].SeasonCount)
+			assert.Equal(t, expected, *response.Response[i].SeasonCount)
+			assert.Nil(t, response.Response[i].Seasons)
+		}
+	})
+}
+
+func TestSeasonWithoutSlugIsDropped(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 24, "slug": "show/thunderbirds", "title": "Thunderbirds",
+			"image": {"showImage": "http://img.example.com/a.jpg"},
+			"seasons": [{"number": 2, "drm": true},
+				{"slug": "show/thunderbirds/season/1", "number": 1, "drm": false}]}
+	]}`
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedSlugs []string
+	}{
+		{name: "The show is kept", query: "?report=true&fields=seasons", expectedSlugs: []string{"show/thunderbirds"}},
+		{name: "The dropped season is not counted", query: "?minSeasons=2", expectedSlugs: nil},
+		{name: "The dropped season is never the latest", query: "?latestSeasonDrm=true", expectedSlugs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			if response.Report == nil {
+				return
+			}
+
+			assert.Len(t, response.Response[0].Seasons, 1)
+			assert.Equal(t, "show/thunderbirds/season/1", response.Response[0].Seasons[0].Slug)
+			assert.Empty(t, response.Report.Rejected)
+			if assert.Len(t, response.Report.Modified, 1) {
+				assert.Equal(t, "seasons[0].slug", response.Report.Modified[0].Field)
+			}
+		})
+	}
+}
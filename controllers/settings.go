@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"time"
+
+	"stan.com/stantest/config"
+)
+
+// settings is the server config the handlers run with
+var settings = config.Default()
+
+// feedLocation is where feed dates without an offset are read
+var feedLocation = time.UTC
+
+// Configure sets the server config used by the handlers, call it before serving
+func Configure(cfg *config.Config) {
+	settings = cfg
+	feedLocation = newFeedLocation(cfg)
+	imageVerifier = newImageVerifier(cfg)
+	responseCache = newResponseCache(cfg)
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/models"
+	"stan.com/stantest/search"
+)
+
+// showsParams are the query parameters GET /api/v1/shows understands,
+// anything else is refused
+var showsParams = append([]string{"drm", "minEpisodes"}, optionParams...)
+
+// ListShows answers GET queries over the stored catalogue with the same
+// processing as DealwithEpisodes. Responses carry an ETag of their content
+// so clients can revalidate instead of downloading again.
+func ListShows(c echo.Context) error {
+	c.Logger().Info("received catalogue query")
+
+	if err := checkShowsParams(c); err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+	opts, err := parseRequestOptions(c)
+	if err == nil {
+		err = parseCriteria(c, &opts, true)
+	}
+	if err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+
+	snapshot := catalogue.Current.Snapshot()
+	if snapshot.Empty() {
+		return c.JSON(http.StatusNotFound, errorBody(c, "No catalogue has been uploaded"))
+	}
+
+	setCatalogueCacheControl(c)
+	key := responseKey(c, nil, snapshot.Version)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, nil)
+	}
+
+	// the catalogue's search index is kept until the next upload
+	opts.IndexKey = snapshot.Version
+	matched, report, err := runEpisodes(c, snapshot.Episodes, opts)
+	if errors.Is(err, errCanceled) {
+		return clientGone(c)
+	}
+	if errors.Is(err, errDeadline) {
+		return c.JSON(http.StatusServiceUnavailable, errorBody(c, "Could not process request: processing deadline exceeded"))
+	}
+	if err != nil {
+		// the catalogue was accepted on upload, this is on us
+		c.Logger().Errorf("stored catalogue can't be processed: %s", err.Error())
+		return c.JSON(http.StatusInternalServerError, errorBody(c, "Could not process catalogue: "+err.Error()))
+	}
+
+	response := models.EpisodeResponse{Response: []models.EpisodeResponseItem{}}
+	for _, result := range matched {
+		response.Response = append(response.Response, result.item)
+	}
+	if opts.Report {
+		response.Report = report
+	}
+	return respond(c, key, response, nil)
+}
+
+// suggestParams are the query parameters GET /api/v1/shows/suggest understands
+var suggestParams = []string{"q", "limit"}
+
+// SuggestShows offers catalogue titles close to what was typed so far,
+// for autocomplete and "did you mean"
+func SuggestShows(c echo.Context) error {
+	if err := checkParams(c, suggestParams); err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: "+err.Error()))
+	}
+	q := c.QueryParam("q")
+	if len(search.Tokenize(q)) == 0 {
+		return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: q must contain a word"))
+	}
+	limit := settings.Search.SuggestLimit
+	if value := c.QueryParam("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query parameter: limit must be a positive integer"))
+		}
+		limit = n
+	}
+
+	snapshot := catalogue.Current.Snapshot()
+	if snapshot.Empty() {
+		return c.JSON(http.StatusNotFound, errorBody(c, "No catalogue has been uploaded"))
+	}
+	setCatalogueCacheControl(c)
+	key := responseKey(c, nil, snapshot.Version)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, nil)
+	}
+
+	index := suggestIndexes.get(snapshot.Version, func() *suggestIndex { return newSuggestIndex(snapshot.Episodes) })
+	response := models.SuggestResponse{Query: q, Suggestions: index.suggest(q, limit)}
+	if len(response.Suggestions) > 0 && response.Suggestions[0].Distance > 0 {
+		response.DidYouMean = response.Suggestions[0].Title
+	}
+	return respond(c, key, response, nil)
+}
+
+// setCatalogueCacheControl lets clients keep catalogue query responses for
+// the catalogue max age. Results depend on the time, the settings and image
+// checks as well as the catalogue, so they are private and revalidated
+// against the ETag of their content, which respond sets.
+func setCatalogueCacheControl(c echo.Context) {
+	if settings.Catalogue.MaxAge > 0 {
+		c.Response().Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(settings.Catalogue.MaxAge.Seconds())))
+	} else {
+		c.Response().Header().Set("Cache-Control", "no-cache")
+	}
+}
+
+// checkShowsParams refuses unknown and repeated parameters, so a typo
+// never silently widens the result
+func checkShowsParams(c echo.Context) error {
+	return checkParams(c, showsParams)
+}
+
+// checkParams refuses query parameters not in allowed and repeated ones
+func checkParams(c echo.Context, allowed []string) error {
+	for name, values := range c.QueryParams() {
+		if !slices.Contains(allowed, name) {
+			return fmt.Errorf("unknown parameter %s", name)
+		}
+		if len(values) > 1 {
+			return fmt.Errorf("%s is given more than once", name)
+		}
+	}
+	return nil
+}
+
+// parseCriteria turns drm and minEpisodes into the base criteria. With
+// defaults they are what POST requests get when not given, otherwise
+// only the ones given apply.
+func parseCriteria(c echo.Context, opts *requestOptions, defaults bool) error {
+	opts.Criteria = []episodeFilter{}
+	if value := c.QueryParam("drm"); value != "" {
+		want, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("drm must be true or false")
+		}
+		opts.Criteria = append(opts.Criteria, drmIs(want))
+	} else if defaults {
+		opts.Criteria = append(opts.Criteria, drmIs(true))
+	}
+	if value := c.QueryParam("minEpisodes"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("minEpisodes must be a non-negative integer")
+		}
+		opts.Criteria = append(opts.Criteria, minEpisodes(n))
+	} else if defaults {
+		opts.Criteria = append(opts.Criteria, minEpisodes(1))
+	}
+	return nil
+}
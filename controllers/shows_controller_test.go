@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/catalogue"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func useCatalogue(t *testing.T, body string) *catalogue.Snapshot {
+	t.Helper()
+	var request models.EpisodeRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+	store := catalogue.NewStore()
+	snapshot, err := store.Replace(request.Payload)
+	assert.NoError(t, err)
+	previous := catalogue.Current
+	catalogue.Current = store
+	t.Cleanup(func() { catalogue.Current = previous })
+	return snapshot
+}
+
+func TestListShows(t *testing.T) {
+	defer Configure(config.Default())
+	useCatalogue(t, `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "Kids Club", "description": "Fun for the family", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 0, "slug": "show/b", "title": "Pilot", "image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": false, "episodeCount": 4, "slug": "show/c", "title": "Open House", "description": "Kids renovate a house", "image": {"showImage": "http://img.example.com/c.jpg"}},
+		{"drm": true, "episodeCount": 3, "slug": "show/d", "title": "Drama", "image": {"showImage": "http://img.example.com/d.jpg"}}
+	]}`)
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedSlugs []string
+		expectedError string
+	}{
+		{
+			name:          "Defaults match POST",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/a", "show/d"},
+		},
+		{
+			name:          "Shows without DRM",
+			query:         "?drm=false",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/c"},
+		},
+		{
+			name:          "Shows without episodes",
+			query:         "?minEpisodes=0",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/a", "show/b", "show/d"},
+		},
+		{
+			name:          "More episodes",
+			query:         "?minEpisodes=2",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/d"},
+		},
+		{
+			name:          "Text query over title and description",
+			query:         "?q=KIDS&drm=false",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/c"},
+		},
+		{
+			name:          "Every term must match",
+			query:         "?q=kids+family",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/a"},
+		},
+		{
+			name:          "Nothing matches",
+			query:         "?q=cooking",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{},
+		},
+		{
+			name:          "Unknown parameter",
+			query:         "?drms=true",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: unknown parameter drms",
+		},
+		{
+			name:          "Repeated parameter",
+			query:         "?drm=true&drm=false",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: drm is given more than once",
+		},
+		{
+			name:          "Invalid drm",
+			query:         "?drm=maybe",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: drm must be true or false",
+		},
+		{
+			name:          "Invalid minEpisodes",
+			query:         "?minEpisodes=-1",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: minEpisodes must be a non-negative integer",
+		},
+		{
+			name:          "Blank q",
+			query:         "?q=+",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: q must contain a word",
+		},
+		{
+			name:          "Episode parameters are validated too",
+			query:         "?minSeasons=x",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: minSeasons must be a non-negative integer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/shows"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, ListShows(c))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedError != "" {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedError, body["error"])
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			slugs := []string{}
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+		})
+	}
+}
+
+func TestListShowsWithoutCatalogue(t *testing.T) {
+	previous := catalogue.Current
+	catalogue.Current = catalogue.NewStore()
+	defer func() { catalogue.Current = previous }()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/shows", nil), rec)
+
+	assert.NoError(t, ListShows(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListShowsConditional(t *testing.T) {
+	snapshot := useCatalogue(t, `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}
+	]}`)
+
+	get := func(query string, header http.Header) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shows"+query, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		assert.NoError(t, ListShows(e.NewContext(req, rec)))
+		return rec
+	}
+
+	first := get("?drm=true&minEpisodes=1", nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.Equal(t, contentETag(first.Body.Bytes()), etag)
+	assert.Equal(t, "private, max-age=60", first.Header().Get("Cache-Control"))
+	assert.Empty(t, first.Header().Get("Last-Modified"))
+
+	// the same content has the same entity, however it was asked for
+	reordered := get("?minEpisodes=1&drm=true", nil)
+	assert.Equal(t, etag, reordered.Header().Get("ETag"))
+	assert.Equal(t, etag, get("?drm=true", nil).Header().Get("ETag"))
+	assert.NotEqual(t, etag, get("?drm=true&report=true", nil).Header().Get("ETag"))
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		expectedCode int
+	}{
+		{"Matching ETag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"Weak matching ETag in a list", http.Header{"If-None-Match": {`"other", W/` + etag}}, http.StatusNotModified},
+		{"Stale ETag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"Dates are not validators", http.Header{"If-Modified-Since": {snapshot.UpdatedAt.Add(time.Hour).Format(http.TimeFormat)}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get("?drm=true&minEpisodes=1", tt.header)
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, etag, rec.Header().Get("ETag"))
+			if tt.expectedCode == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestListShowsRevalidatesOverTime(t *testing.T) {
+	useCatalogue(t, `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"},
+			"nextEpisode": {"date": "2024-03-02T12:00:00Z"}}
+	]}`)
+	defer func() { now = time.Now }()
+
+	get := func(at time.Time, header http.Header) *httptest.ResponseRecorder {
+		now = func() time.Time { return at }
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shows?airsWithinDays=7", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		assert.NoError(t, ListShows(e.NewContext(req, rec)))
+		return rec
+	}
+
+	before := get(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), nil)
+	assert.Contains(t, before.Body.String(), "show/a")
+
+	// the catalogue is unchanged, but the episode has aired since
+	after := get(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), http.Header{"If-None-Match": {before.Header().Get("ETag")}})
+	assert.Equal(t, http.StatusOK, after.Code)
+	assert.NotContains(t, after.Body.String(), "show/a")
+	assert.NotEqual(t, before.Header().Get("ETag"), after.Header().Get("ETag"))
+}
+
+func TestSuggestShows(t *testing.T) {
+	useCatalogue(t, `{"payload": [
+		{"drm": true, "episodeCount": 24, "slug": "show/thunderbirds", "title": "Thunderbirds"},
+		{"drm": true, "episodeCount": 12, "slug": "show/thunderbirdsarego", "title": "Thunderbirds Are Go"},
+		{"drm": true, "episodeCount": 3, "slug": "show/thetaste", "title": "The Taste"},
+		{"drm": false, "episodeCount": 3, "slug": "show/thunder", "title": "Thunder"},
+		{"drm": true, "episodeCount": 8, "slug": "show/legout", "title": "Le Goût"}
+	]}`)
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedSlugs []string
+		expectedGuess string
+		expectedError string
+	}{
+		{
+			name:          "Unfinished word",
+			query:         "?q=thunderb",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/thunderbirds", "show/thunderbirdsarego"},
+		},
+		{
+			name:          "Did you mean",
+			query:         "?q=the+tsate",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/thetaste"},
+			expectedGuess: "The Taste",
+		},
+		{
+			name:          "Accents",
+			query:         "?q=le+gout",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/legout"},
+		},
+		{
+			name:          "Limit",
+			query:         "?q=thunderbirds&limit=1",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/thunderbirds"},
+		},
+		{
+			name:          "Nothing close",
+			query:         "?q=weather",
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{},
+		},
+		{
+			name:          "Missing q",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: q must contain a word",
+		},
+		{
+			name:          "Invalid limit",
+			query:         "?q=thunder&limit=0",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: limit must be a positive integer",
+		},
+		{
+			name:          "Unknown parameter",
+			query:         "?q=thunder&drm=false",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid query parameter: unknown parameter drm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/shows/suggest"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, SuggestShows(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedError != "" {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedError, body["error"])
+				return
+			}
+
+			var response models.SuggestResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			slugs := []string{}
+			for _, suggestion := range response.Suggestions {
+				slugs = append(slugs, suggestion.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			assert.Equal(t, tt.expectedGuess, response.DidYouMean)
+			assert.NotEmpty(t, rec.Header().Get("ETag"))
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+// slugPattern is lower case words joined by dashes, in slash separated segments
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*(?:/[a-z0-9]+(?:-[a-z0-9]+)*)*$`)
+
+var (
+	repeatedSlashes = regexp.MustCompile(`/{2,}`)
+	// underscores and dots, which older slugs use between words
+	wordSeparators = regexp.MustCompile(`[_.]+`)
+	repeatedDashes = regexp.MustCompile(`-{2,}`)
+	// dashes at either end of a segment
+	segmentDashes = regexp.MustCompile(`-*/-*`)
+)
+
+// canonicalSlug trims, lower cases, turns underscores and dots into dashes
+// and tidies up slashes, e.g. " Show//Mr_Robot/ " becomes "show/mr-robot"
+func canonicalSlug(slug string) string {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	slug = wordSeparators.ReplaceAllString(slug, "-")
+	slug = repeatedDashes.ReplaceAllString(slug, "-")
+	slug = repeatedSlashes.ReplaceAllString(slug, "/")
+	slug = segmentDashes.ReplaceAllString(slug, "/")
+	return strings.Trim(slug, "/-")
+}
+
+// validateSlug checks a canonical slug against the grammar and required prefix
+func validateSlug(slug, prefix string) error {
+	if !slugPattern.MatchString(slug) {
+		return echo.NewHTTPError(400, "slug may only contain a-z, 0-9 and dashes in slash separated segments")
+	}
+	if prefix != "" && (!strings.HasPrefix(slug, prefix) || len(slug) == len(prefix)) {
+		return echo.NewHTTPError(400, fmt.Sprintf("slug must start with %q", prefix))
+	}
+	return nil
+}
+
+// canonicalizeSlug rewrites the episode slug into canonical form
+func canonicalizeSlug(episode *models.Episode) []models.ValidationIssue {
+	canonical := canonicalSlug(episode.Slug)
+	if canonical == episode.Slug {
+		return nil
+	}
+	issue := models.ValidationIssue{Slug: canonical, Field: "slug", Message: "normalised " + episode.Slug + " to " + canonical}
+	episode.Slug = canonical
+	return []models.ValidationIssue{issue}
+}
+
+// rejectDuplicates fails with the reject policy when any two episodes
+// share a canonical slug. It looks at the whole payload, whether or not
+// the duplicates would have matched.
+func rejectDuplicates(episodes []models.Episode, policy string) error {
+	if policy != config.DUPLICATE_POLICY_REJECT {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, episode := range episodes {
+		slug := canonicalSlug(episode.Slug)
+		// empty slugs are left to validation
+		if slug == "" {
+			continue
+		}
+		if seen[slug] {
+			return fmt.Errorf("duplicate slug %s", slug)
+		}
+		seen[slug] = true
+	}
+	return nil
+}
+
+// mergeDuplicates folds episodes sharing a canonical slug into the first
+// of them with the merge policy, before they are validated. positions
+// holds where each kept episode was in episodes.
+func mergeDuplicates(episodes []models.Episode, policy string) (kept []models.Episode, positions []int, modified []models.ValidationIssue) {
+	kept = make([]models.Episode, 0, len(episodes))
+	positions = make([]int, 0, len(episodes))
+	// positions in kept of the episode kept for each slug
+	index := map[string]int{}
+	for i, episode := range episodes {
+		slug := canonicalSlug(episode.Slug)
+		first, seen := index[slug]
+		if policy == config.DUPLICATE_POLICY_MERGE && seen && slug != "" {
+			kept[first] = mergeEpisodes(kept[first], episode)
+			modified = append(modified, models.ValidationIssue{Slug: slug, Field: "slug", Message: "duplicate slug merged into first episode"})
+			continue
+		}
+		index[slug] = len(kept)
+		kept = append(kept, episode)
+		positions = append(positions, i)
+	}
+	return kept, positions, modified
+}
+
+// dropDuplicates keeps one of the results sharing a slug, the first or
+// with the keep-last policy the last. Results have passed validation, so
+// an invalid duplicate never takes the place of a valid one, and are in
+// payload order, which is kept.
+func dropDuplicates(results []episodeResult, policy string) (kept []episodeResult, rejected []models.ValidationIssue) {
+	if policy != config.DUPLICATE_POLICY_KEEP_FIRST && policy != config.DUPLICATE_POLICY_KEEP_LAST {
+		return results, nil
+	}
+	// the result kept for each slug
+	chosen := map[string]int{}
+	for i, result := range results {
+		slug := result.episode.Slug
+		if _, seen := chosen[slug]; !seen {
+			chosen[slug] = i
+			continue
+		}
+		if policy == config.DUPLICATE_POLICY_KEEP_LAST {
+			chosen[slug] = i
+			rejected = append(rejected, models.ValidationIssue{Slug: slug, Field: "slug", Message: "duplicate slug, earlier episode dropped"})
+			continue
+		}
+		rejected = append(rejected, models.ValidationIssue{Slug: slug, Field: "slug", Message: "duplicate slug, later episode dropped"})
+	}
+	if rejected == nil {
+		return results, nil
+	}
+
+	kept = make([]episodeResult, 0, len(chosen))
+	for i, result := range results {
+		if chosen[result.episode.Slug] == i {
+			kept = append(kept, result)
+		}
+	}
+	return kept, rejected
+}
+
+// mergeEpisodes fills whatever base is missing from other,
+// image renditions and seasons are combined
+func mergeEpisodes(base, other models.Episode) models.Episode {
+	// never append into the decoded request
+	base.Image.ImageSet = slices.Clone(base.Image.ImageSet)
+	base.Seasons = slices.Clone(base.Seasons)
+
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&base.Country, other.Country)
+	fill(&base.Description, other.Description)
+	fill(&base.Genre, other.Genre)
+	fill(&base.Language, other.Language)
+	fill(&base.PrimaryColor, other.PrimaryColor)
+	fill(&base.Title, other.Title)
+	fill(&base.TVChannel, other.TVChannel)
+	fill(&base.Image.ShowImage, other.Image.ShowImage)
+
+	base.DRM = base.DRM || other.DRM
+	base.EpisodeCount = max(base.EpisodeCount, other.EpisodeCount)
+	if base.NextEpisode == nil {
+		base.NextEpisode = other.NextEpisode
+	}
+
+	urls := map[string]bool{}
+	for _, rendition := range base.Image.ImageSet {
+		urls[rendition.URL] = true
+	}
+	for _, rendition := range other.Image.ImageSet {
+		if !urls[rendition.URL] {
+			base.Image.ImageSet = append(base.Image.ImageSet, rendition)
+		}
+	}
+
+	seasons := map[string]bool{}
+	for _, season := range base.Seasons {
+		seasons[season.Slug] = true
+	}
+	for _, season := range other.Seasons {
+		if !seasons[season.Slug] {
+			base.Seasons = append(base.Seasons, season)
+		}
+	}
+	return base
+}
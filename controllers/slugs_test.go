@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestCanonicalSlug(t *testing.T) {
+	assert.Equal(t, "show/16kidsandcounting", canonicalSlug("show/16kidsandcounting"))
+	assert.Equal(t, "show/16kidsandcounting", canonicalSlug(" Show//16KidsAndCounting/ "))
+	assert.Equal(t, "show/thetaste/season/1", canonicalSlug("/show/thetaste//season/1"))
+	assert.Equal(t, "show/mr-robot", canonicalSlug("show/mr_robot"))
+	assert.Equal(t, "show/mr-robot/season/1-5", canonicalSlug("show/Mr._Robot/season/_1.5"))
+	assert.NoError(t, validateSlug(canonicalSlug("show/the_office.us"), "show/"))
+}
+
+func TestDedupeEpisodes(t *testing.T) {
+	episodes := []models.Episode{
+		{Slug: "show/thetaste", Title: "The Taste", Genre: ""},
+		{Slug: "show/thunderbirds", Title: "Thunderbirds"},
+		{Slug: "Show/TheTaste", Title: "The Taste (Le Goût)", Genre: "Reality",
+			Seasons: []models.Season{{Slug: "show/thetaste/season/1"}}},
+	}
+	// results carry canonical slugs, as processEpisode leaves them
+	results := make([]episodeResult, len(episodes))
+	for i, episode := range episodes {
+		episode.Slug = canonicalSlug(episode.Slug)
+		results[i] = episodeResult{episode: episode}
+	}
+
+	tests := []struct {
+		name             string
+		policy           string
+		wantErr          bool
+		expectedTitles   []string
+		expectedPosition []int
+		expectedRejected int
+		expectedModified int
+	}{
+		{
+			name:             "Keep first",
+			policy:           config.DUPLICATE_POLICY_KEEP_FIRST,
+			expectedTitles:   []string{"The Taste", "Thunderbirds"},
+			expectedPosition: []int{0, 1, 2},
+			expectedRejected: 1,
+		},
+		{
+			name:             "Keep last",
+			policy:           config.DUPLICATE_POLICY_KEEP_LAST,
+			expectedTitles:   []string{"Thunderbirds", "The Taste (Le Goût)"},
+			expectedPosition: []int{0, 1, 2},
+			expectedRejected: 1,
+		},
+		{
+			name:             "Merge",
+			policy:           config.DUPLICATE_POLICY_MERGE,
+			expectedTitles:   []string{"The Taste", "Thunderbirds"},
+			expectedPosition: []int{0, 1},
+			expectedModified: 1,
+		},
+		{
+			name:    "Reject",
+			policy:  config.DUPLICATE_POLICY_REJECT,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rejectDuplicates(episodes, tt.policy)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "duplicate slug show/thetaste")
+				return
+			}
+			assert.NoError(t, err)
+
+			merged, positions, modified := mergeDuplicates(episodes, tt.policy)
+			assert.Equal(t, tt.expectedPosition, positions)
+			assert.Len(t, modified, tt.expectedModified)
+			if tt.policy == config.DUPLICATE_POLICY_MERGE {
+				assert.Equal(t, "Reality", merged[0].Genre)
+				assert.Len(t, merged[0].Seasons, 1)
+			}
+
+			var titles []string
+			for _, episode := range merged {
+				titles = append(titles, episode.Title)
+			}
+			// merged episodes are processed once, so no duplicates are left to drop
+			kept, rejected := dropDuplicates(results, tt.policy)
+			if tt.policy != config.DUPLICATE_POLICY_MERGE {
+				titles = nil
+				for _, result := range kept {
+					titles = append(titles, result.episode.Title)
+				}
+			}
+			assert.Equal(t, tt.expectedTitles, titles)
+			assert.Len(t, rejected, tt.expectedRejected)
+		})
+	}
+	// the input is never modified
+	assert.Empty(t, episodes[0].Genre)
+	assert.Empty(t, episodes[0].Seasons)
+}
+
+func TestDuplicateSlugsRejectRequest(t *testing.T) {
+	cfg := config.Default()
+	cfg.Validation.DuplicatePolicy = config.DUPLICATE_POLICY_REJECT
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/A/", "title": "A again", "image": {"showImage": "http://img.example.com/a.jpg"}}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, DealwithEpisodes(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var errorResponse map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Contains(t, errorResponse["error"], "duplicate slug show/a")
+}
+
+func TestDuplicatesResolvedAmongMatches(t *testing.T) {
+	// the first show/a doesn't match the default criteria, the second does
+	body := `{"payload": [
+		{"drm": false, "episodeCount": 1, "slug": "show/a", "title": "A without DRM", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 0, "slug": "show/b", "title": "B without episodes", "image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": true, "episodeCount": 0, "slug": "show/b", "title": "B again", "image": {"showImage": "http://img.example.com/b.jpg"}}
+	]}`
+
+	for _, policy := range []string{config.DUPLICATE_POLICY_KEEP_FIRST, config.DUPLICATE_POLICY_KEEP_LAST} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.DuplicatePolicy = policy
+			Configure(cfg)
+			defer Configure(config.Default())
+
+			rec := post(DealwithEpisodes, "/api/v1/episodes?report=true", body, nil)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response struct {
+				Response []models.EpisodeResponseItem `json:"response"`
+				Report   models.ValidationReport      `json:"report"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			if assert.Len(t, response.Response, 1) {
+				assert.Equal(t, "A", response.Response[0].Title)
+			}
+			// duplicates the filters left out are not reported
+			assert.Empty(t, response.Report.Rejected)
+		})
+	}
+}
+
+func TestDuplicateRejectCoversThePayload(t *testing.T) {
+	cfg := config.Default()
+	cfg.Validation.DuplicatePolicy = config.DUPLICATE_POLICY_REJECT
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	// the first show/a is filtered out, it still makes the payload ambiguous
+	body := `{"payload": [
+		{"drm": false, "episodeCount": 1, "slug": "show/a", "title": "A without DRM", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}}
+	]}`
+	rec := post(DealwithEpisodes, "/api/v1/episodes", body, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "duplicate slug show/a")
+}
+
+func TestDuplicatesResolvedAmongValidEpisodes(t *testing.T) {
+	// the first show/a has no image and fails validation, the second is fine
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A without an image"},
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A again"}
+	]}`
+
+	for _, policy := range []string{config.DUPLICATE_POLICY_KEEP_FIRST, config.DUPLICATE_POLICY_KEEP_LAST} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.DuplicatePolicy = policy
+			Configure(cfg)
+			defer Configure(config.Default())
+
+			rec := post(DealwithEpisodes, "/api/v1/episodes?report=true", body, nil)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response struct {
+				Response []models.EpisodeResponseItem `json:"response"`
+				Report   models.ValidationReport      `json:"report"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			if assert.Len(t, response.Response, 1) {
+				assert.Equal(t, "A", response.Response[0].Title)
+			}
+			// both invalid episodes are rejected for their own problem, not as duplicates
+			assert.Len(t, response.Report.Rejected, 2)
+			for _, issue := range response.Report.Rejected {
+				assert.NotContains(t, issue.Message, "duplicate")
+			}
+		})
+	}
+}
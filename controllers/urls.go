@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/models"
+	"stan.com/stantest/urlpolicy"
+)
+
+// urlPolicy is the URL policy of the current settings
+func urlPolicy() *urlpolicy.Policy {
+	cfg := settings.Validation.URL
+	return &urlpolicy.Policy{
+		AllowHosts:       cfg.AllowHosts,
+		DenyHosts:        cfg.DenyHosts,
+		HTTPSOnly:        cfg.HTTPSOnly,
+		UpgradeHTTP:      cfg.UpgradeHTTP,
+		RejectIPLiterals: cfg.RejectIPLiterals,
+		RejectPrivate:    cfg.RejectPrivate,
+		MaxLength:        cfg.MaxLength,
+		ImageExtensions:  cfg.ImageExtensions,
+	}
+}
+
+// checkURL validates a link, or an image URL when image is set,
+// the error names every rule the URL broke
+func checkURL(field, raw string, image bool) error {
+	var result urlpolicy.Result
+	if image {
+		result = urlPolicy().CheckImage(raw)
+	} else {
+		result = urlPolicy().Check(raw)
+	}
+	if result.OK() {
+		return nil
+	}
+	if slices.Contains(result.Violations, urlpolicy.Invalid) {
+		return echo.NewHTTPError(400, field+" must be a valid URL")
+	}
+	names := make([]string, len(result.Violations))
+	for i, violation := range result.Violations {
+		names[i] = string(violation)
+	}
+	return echo.NewHTTPError(400, fmt.Sprintf("%s violates URL policy: %s", field, strings.Join(names, ", ")))
+}
+
+// applyURLPolicy rewrites the URLs of a validated episode which the policy
+// upgrades to https, each rewrite is returned as an issue for the report
+func applyURLPolicy(episode *models.Episode) []models.ValidationIssue {
+	policy := urlPolicy()
+	if !policy.UpgradeHTTP {
+		return nil
+	}
+
+	var issues []models.ValidationIssue
+	upgrade := func(field string, value *string) {
+		if *value == "" {
+			return
+		}
+		if result := policy.Check(*value); result.Rewritten {
+			*value = result.URL
+			issues = append(issues, models.ValidationIssue{Slug: episode.Slug, Field: field, Message: "upgraded to https"})
+		}
+	}
+
+	upgrade("image.showImage", &episode.Image.ShowImage)
+	if len(episode.Image.ImageSet) > 0 {
+		// never write through to the decoded request
+		episode.Image.ImageSet = slices.Clone(episode.Image.ImageSet)
+		for i := range episode.Image.ImageSet {
+			upgrade(fmt.Sprintf("image.imageSet[%d].url", i), &episode.Image.ImageSet[i].URL)
+		}
+	}
+	if episode.NextEpisode != nil {
+		next := *episode.NextEpisode
+		episode.NextEpisode = &next
+		upgrade("nextEpisode.url", &next.URL)
+		upgrade("nextEpisode.channelLogo", &next.ChannelLogo)
+	}
+	return issues
+}
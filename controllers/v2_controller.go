@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/logging"
+	"stan.com/stantest/models"
+)
+
+// FilterShows is the v2 episode endpoint. It shares the processing core
+// with v1 and answers with a data, meta and errors envelope.
+func FilterShows(c echo.Context) error {
+	c.Logger().Info("received v2 show processing request")
+
+	rawBody, err := readBody(c)
+	if err != nil {
+		return showsError(c, bodyStatus(err), models.APIError{Code: bodyErrorCode(err), Message: err.Error()})
+	}
+	key := responseKey(c, rawBody)
+	if cached, ok := lookupResponse(c, key); ok {
+		return sendCached(c, cached, withRequestID(c))
+	}
+
+	if settings.Validation.Schema {
+		violations, err := showsSchema.ValidateJSON(rawBody)
+		if err == nil && len(violations) > 0 {
+			c.Logger().Errorf("request does not match the schema: %d violations", len(violations))
+			apiErrors := make([]models.APIError, len(violations))
+			for i, violation := range violations {
+				apiErrors[i] = models.APIError{Code: models.ErrorCodeSchema, Message: violation.Message, Pointer: violation.Pointer}
+			}
+			return showsError(c, http.StatusBadRequest, apiErrors...)
+		}
+	}
+
+	var request models.ShowsRequest
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		c.Logger().Errorf("failed to unmarshal request: %s", err.Error())
+		return showsError(c, http.StatusBadRequest, models.APIError{Code: models.ErrorCodeInvalidBody, Message: "JSON parsing failed"})
+	}
+	if request.Data == nil {
+		return showsError(c, http.StatusBadRequest, models.APIError{Code: models.ErrorCodeInvalidBody, Message: "data is required", Pointer: "/data"})
+	}
+
+	opts, err := parseShowOptions(c)
+	if err == nil {
+		err = parsePage(c, &opts)
+	}
+	if err != nil {
+		c.Logger().Errorf("invalid query parameters: %s", err.Error())
+		return showsError(c, http.StatusBadRequest, models.APIError{Code: models.ErrorCodeInvalidQuery, Message: err.Error()})
+	}
+
+	payload := make([]models.Episode, len(request.Data))
+	for i, show := range request.Data {
+		payload[i] = show.Episode()
+	}
+	matched, report, err := runEpisodes(c, payload, opts)
+	if errors.Is(err, errCanceled) {
+		return clientGone(c)
+	}
+	if errors.Is(err, errDeadline) {
+		return showsError(c, http.StatusServiceUnavailable, models.APIError{Code: models.ErrorCodeDeadline, Message: err.Error()})
+	}
+	if err != nil {
+		return showsError(c, http.StatusBadRequest, models.APIError{Code: models.ErrorCodeInvalidBody, Message: err.Error()})
+	}
+
+	response := models.ShowsResponse{
+		Data:   []models.ShowItem{},
+		Errors: []models.APIError{},
+	}
+	page := pageOf(matched, opts.Offset, opts.Limit)
+	for _, result := range page {
+		response.Data = append(response.Data, models.NewShowItem(result.item))
+	}
+	response.Meta.Page = models.Page{Total: len(matched), Offset: opts.Offset, Limit: opts.Limit, Count: len(page)}
+	// rejected shows are errors in v2, the rest of the report is opt-in
+	for _, issue := range report.Rejected {
+		response.Errors = append(response.Errors, models.APIError{
+			Code: models.ErrorCodeRejected, Message: issue.Message, Slug: issue.Slug, Field: showField(issue.Field), Rule: issue.Rule,
+		})
+	}
+	if opts.Report {
+		response.Meta.Report = &models.MetaReport{Modified: showIssues(report.Modified), Warnings: showIssues(report.Warnings)}
+	}
+
+	// the request id is left out of the ETag and the cache
+	return respond(c, key, response, withRequestID(c))
+}
+
+// showIssues spells the fields of report issues as v2 does
+func showIssues(issues []models.ValidationIssue) []models.ValidationIssue {
+	spelled := make([]models.ValidationIssue, len(issues))
+	for i, issue := range issues {
+		issue.Field = showField(issue.Field)
+		spelled[i] = issue
+	}
+	return spelled
+}
+
+// withRequestID adds the id of the request being answered to the body of
+// a ShowsResponse. Only meta is decoded, data and errors are copied as
+// they are.
+func withRequestID(c echo.Context) func([]byte) ([]byte, error) {
+	return func(body []byte) ([]byte, error) {
+		var response struct {
+			Data   json.RawMessage `json:"data"`
+			Meta   models.Meta     `json:"meta"`
+			Errors json.RawMessage `json:"errors"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		response.Meta.RequestID = logging.RequestID(c)
+		return json.Marshal(response)
+	}
+}
+
+// bodyErrorCode is the v2 error code for a readBody error
+func bodyErrorCode(err error) string {
+	switch bodyStatus(err) {
+	case http.StatusRequestEntityTooLarge:
+		return models.ErrorCodeBodyTooLarge
+	case http.StatusUnsupportedMediaType:
+		return models.ErrorCodeUnsupportedEncoding
+	}
+	return models.ErrorCodeInvalidBody
+}
+
+// showsError answers a failed v2 request
+func showsError(c echo.Context, status int, apiErrors ...models.APIError) error {
+	return c.JSON(status, models.ShowsResponse{
+		Meta:   models.Meta{RequestID: logging.RequestID(c)},
+		Errors: apiErrors,
+	})
+}
+
+// parsePage reads the offset and limit pagination parameters
+func parsePage(c echo.Context, opts *requestOptions) error {
+	if offset := c.QueryParam("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return fmt.Errorf("offset must be a non-negative integer")
+		}
+		opts.Offset = n
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return fmt.Errorf("limit must be a non-negative integer")
+		}
+		opts.Limit = n
+	}
+	return nil
+}
+
+// pageOf cuts a page out of the matched episodes, a zero limit means all
+func pageOf(matched []episodeResult, offset, limit int) []episodeResult {
+	if offset >= len(matched) {
+		return nil
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched
+}
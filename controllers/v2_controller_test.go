@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/models"
+)
+
+func TestFilterShows(t *testing.T) {
+	defer Configure(config.Default())
+
+	body := `{"data": [
+		{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "primaryColor": "red", "image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 1, "slug": "show/b", "title": "", "image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": true, "episodeCount": 2, "slug": "show/c", "title": "C", "image": {"showImage": "http://img.example.com/c.jpg"}},
+		{"drm": false, "episodeCount": 2, "slug": "show/d", "title": "D", "image": {"showImage": "http://img.example.com/d.jpg"}},
+		{"drm": true, "episodeCount": 3, "slug": "show/e", "title": "E", "image": {"showImage": "http://img.example.com/e.jpg"}}
+	]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		schema         bool
+		expectedCode   int
+		expectedSlugs  []string
+		expectedPage   models.Page
+		expectedErrors []models.APIError
+	}{
+		{
+			name:          "All shows",
+			query:         "?fields=primaryColor,textColor",
+			body:          body,
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/a", "show/c", "show/e"},
+			expectedPage:  models.Page{Total: 3, Count: 3},
+			expectedErrors: []models.APIError{
+				{Code: models.ErrorCodeRejected, Message: "title is required", Slug: "show/b"},
+			},
+		},
+		{
+			name:          "Second page",
+			query:         "?offset=1&limit=1",
+			body:          body,
+			expectedCode:  http.StatusOK,
+			expectedSlugs: []string{"show/c"},
+			expectedPage:  models.Page{Total: 3, Offset: 1, Limit: 1, Count: 1},
+			expectedErrors: []models.APIError{
+				{Code: models.ErrorCodeRejected, Message: "title is required", Slug: "show/b"},
+			},
+		},
+		{
+			name:           "Past the last page",
+			query:          "?offset=10",
+			body:           `{"data": []}`,
+			expectedCode:   http.StatusOK,
+			expectedSlugs:  []string{},
+			expectedPage:   models.Page{Offset: 10},
+			expectedErrors: []models.APIError{},
+		},
+		{
+			name:           "Invalid paging",
+			query:          "?limit=-1",
+			body:           body,
+			expectedCode:   http.StatusBadRequest,
+			expectedErrors: []models.APIError{{Code: models.ErrorCodeInvalidQuery, Message: "limit must be a non-negative integer"}},
+		},
+		{
+			name:           "Missing data",
+			body:           `{"payload": []}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedErrors: []models.APIError{{Code: models.ErrorCodeInvalidBody, Message: "data is required", Pointer: "/data"}},
+		},
+		{
+			name:           "Not JSON",
+			body:           `{"data": [`,
+			expectedCode:   http.StatusBadRequest,
+			expectedErrors: []models.APIError{{Code: models.ErrorCodeInvalidBody, Message: "JSON parsing failed"}},
+		},
+		{
+			name:           "Schema violation",
+			body:           `{"data": [{"drm": 1}]}`,
+			schema:         true,
+			expectedCode:   http.StatusBadRequest,
+			expectedErrors: []models.APIError{{Code: models.ErrorCodeSchema, Message: "expected boolean, got integer", Pointer: "/data/0/drm"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Validation.Schema = tt.schema
+			Configure(cfg)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/shows"+tt.query, bytes.NewBufferString(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, FilterShows(c))
+			assert.Equal(t, tt.expectedCode, rec.Code)
+
+			var response models.ShowsResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedErrors, response.Errors)
+			if tt.expectedCode != http.StatusOK {
+				assert.Nil(t, response.Data)
+				return
+			}
+
+			slugs := []string{}
+			for _, item := range response.Data {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+			assert.Equal(t, tt.expectedPage, response.Meta.Page)
+			assert.Nil(t, response.Meta.Report)
+		})
+	}
+}
+
+func TestFilterShowsCamelCaseFields(t *testing.T) {
+	e := echo.New()
+	body := `{"data": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "primaryColor": "red",
+		"image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/shows?fields=primaryColor,textColor&report=true", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, FilterShows(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var raw map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+	item := raw["data"].([]any)[0].(map[string]any)
+	assert.Equal(t, "#ff0000", item["primaryColor"])
+	assert.Equal(t, "#000000", item["textColor"])
+	assert.NotContains(t, item, "primaryColour")
+
+	meta := raw["meta"].(map[string]any)
+	report := meta["report"].(map[string]any)
+	if assert.Len(t, report["modified"], 1) {
+		assert.Equal(t, "primaryColor", report["modified"].([]any)[0].(map[string]any)["field"])
+	}
+}
+
+func TestFilterShowsFieldSpelling(t *testing.T) {
+	cfg := config.Default()
+	cfg.Validation.ColourPolicy = config.COLOUR_POLICY_REJECT
+	Configure(cfg)
+	defer Configure(config.Default())
+
+	body := `{"data": [{"drm": true, "episodeCount": 1, "slug": "show/a", "title": "A", "primaryColor": "orangeish",
+		"image": {"showImage": "http://img.example.com/a.jpg"}}]}`
+	var response models.ShowsResponse
+	rec := post(FilterShows, "/api/v2/shows", body, nil)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []models.APIError{
+		{Code: models.ErrorCodeRejected, Message: "must be a valid colour", Slug: "show/a", Field: "primaryColor"},
+	}, response.Errors)
+
+	rec = post(FilterShows, "/api/v2/shows?fields=primaryColour", body, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	response = models.ShowsResponse{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []models.APIError{{
+		Code:    models.ErrorCodeInvalidQuery,
+		Message: "fields must be a comma separated list of [nextEpisode seasons seasonCount primaryColor textColor country language genre]",
+	}}, response.Errors)
+
+	// v1 keeps its own spelling
+	rec = post(DealwithEpisodes, "/api/v1/episodes?fields=primaryColor", `{"payload": []}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"time"
+
+	"stan.com/stantest/models"
+	"stan.com/stantest/vocab"
+)
+
+// normalizeVocabularies maps country, language and genre onto their
+// canonical codes. Unknown values are kept as sent and flagged as warnings.
+func normalizeVocabularies(episode *models.Episode) (modified, warnings []models.ValidationIssue) {
+	normalize := func(field string, value *string, lookup func(string) (string, bool)) {
+		if *value == "" {
+			return
+		}
+		code, ok := lookup(*value)
+		if !ok {
+			warnings = append(warnings, models.ValidationIssue{Slug: episode.Slug, Field: field, Message: "unknown value " + *value})
+			return
+		}
+		if code != *value {
+			modified = append(modified, models.ValidationIssue{Slug: episode.Slug, Field: field, Message: "normalised " + *value + " to " + code})
+			*value = code
+		}
+	}
+
+	normalize("country", &episode.Country, vocab.Country)
+	normalize("language", &episode.Language, vocab.Language)
+	normalize("genre", &episode.Genre, vocab.Genres.Normalize)
+	return modified, warnings
+}
+
+// countryIs keeps episodes from the given ISO 3166 country
+func countryIs(code string) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		country, ok := vocab.Country(episode.Country)
+		return ok && country == code
+	}
+}
+
+// languageIs keeps episodes in the given language, regional variants included
+func languageIs(tag string) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		lang, ok := vocab.Language(episode.Language)
+		return ok && vocab.LanguageMatches(lang, tag)
+	}
+}
+
+// genreIs keeps episodes of the given genre code
+func genreIs(code string) episodeFilter {
+	return func(episode models.Episode, _ time.Time) bool {
+		genre, ok := vocab.Genres.Normalize(episode.Genre)
+		return ok && genre == code
+	}
+}
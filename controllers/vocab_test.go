@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+func TestVocabularies(t *testing.T) {
+	e := echo.New()
+	body := `{"payload": [
+		{"drm": true, "episodeCount": 3, "slug": "show/16kidsandcounting", "title": "16 Kids and Counting",
+			"country": "UK", "language": "English", "genre": "Reality",
+			"image": {"showImage": "http://img.example.com/a.jpg"}},
+		{"drm": true, "episodeCount": 2, "slug": "show/thetaste", "title": "The Taste",
+			"country": " USA", "language": "en-us", "genre": "Cooking",
+			"image": {"showImage": "http://img.example.com/b.jpg"}},
+		{"drm": true, "episodeCount": 24, "slug": "show/thunderbirds", "title": "Thunderbirds",
+			"country": "United Kingdom", "language": "fr", "genre": "action",
+			"image": {"showImage": "http://img.example.com/c.jpg"}}
+	]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSlugs  []string
+	}{
+		{
+			name:           "Country alias and name both match",
+			query:          "?country=GB",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/16kidsandcounting", "show/thunderbirds"},
+		},
+		{
+			name:           "Filter values are normalised too",
+			query:          "?country=united%20states",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/thetaste"},
+		},
+		{
+			name:           "Language matches regional variants",
+			query:          "?language=en",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/16kidsandcounting", "show/thetaste"},
+		},
+		{
+			name:           "Genre code",
+			query:          "?genre=reality",
+			expectedStatus: http.StatusOK,
+			expectedSlugs:  []string{"show/16kidsandcounting"},
+		},
+		{
+			name:           "Unknown country filter",
+			query:          "?country=Narnia",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes"+tt.query, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			assert.NoError(t, DealwithEpisodes(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.EpisodeResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			var slugs []string
+			for _, item := range response.Response {
+				slugs = append(slugs, item.Slug)
+			}
+			assert.Equal(t, tt.expectedSlugs, slugs)
+		})
+	}
+
+	t.Run("Canonical codes and unknown values in the report", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/episodes?fields=country,language,genre&report=true", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		assert.NoError(t, DealwithEpisodes(c))
+		var response models.EpisodeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Len(t, response.Response, 3)
+
+		first := response.Response[0]
+		assert.Equal(t, "GB", first.Country)
+		assert.Equal(t, "en", first.Language)
+		assert.Equal(t, "reality", first.Genre)
+		// unknown genre is passed through as sent
+		assert.Equal(t, "Cooking", response.Response[1].Genre)
+
+		assert.Len(t, response.Report.Warnings, 1)
+		assert.Equal(t, "genre", response.Report.Warnings[0].Field)
+		assert.Equal(t, "show/thetaste", response.Report.Warnings[0].Slug)
+	})
+}
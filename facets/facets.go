@@ -0,0 +1,231 @@
+package facets
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"stan.com/stantest/models"
+	"stan.com/stantest/vocab"
+)
+
+// names of the facets episodes can be counted by
+const (
+	Genre        = "genre"
+	Country      = "country"
+	Language     = "language"
+	TVChannel    = "tvChannel"
+	DRM          = "drm"
+	EpisodeCount = "episodeCount"
+)
+
+// Names lists every facet
+var Names = []string{Genre, Country, Language, TVChannel, DRM, EpisodeCount}
+
+// DefaultEpisodeBuckets are the lower bounds of the episodeCount buckets
+// 0, 1-5, 6-10, 11-25, 26-50 and 51+
+var DefaultEpisodeBuckets = []int{1, 6, 11, 26, 51}
+
+// Options pick what an Aggregator counts
+type Options struct {
+	// Facets to count, nil counts all of them
+	Facets []string
+	// Pivot nests the counts of a second facet in those of a first, like
+	// genres per country, empty counts no pivot
+	Pivot []string
+	// EpisodeBuckets are ascending positive lower bounds, the first bucket
+	// starts at 0. Nil uses DefaultEpisodeBuckets.
+	EpisodeBuckets []int
+}
+
+// Aggregator counts facets and episodeCount statistics over episodes
+// added one at a time, so a payload is only gone through once
+type Aggregator struct {
+	facets  []string
+	pivot   []string
+	buckets []int
+	labels  []string
+
+	total   int
+	counts  map[string]map[string]int
+	missing map[string]int
+	pivots  map[string]map[string]int
+	stats   models.Stats
+}
+
+// New checks the options and returns an empty aggregator
+func New(opts Options) (*Aggregator, error) {
+	facets := opts.Facets
+	if facets == nil {
+		facets = Names
+	}
+	for _, name := range append(slices.Clone(facets), opts.Pivot...) {
+		if !slices.Contains(Names, name) {
+			return nil, fmt.Errorf("unknown facet %s, facets are %v", name, Names)
+		}
+	}
+	if len(opts.Pivot) != 0 && (len(opts.Pivot) != 2 || opts.Pivot[0] == opts.Pivot[1]) {
+		return nil, fmt.Errorf("a pivot needs two different facets")
+	}
+
+	buckets := opts.EpisodeBuckets
+	if buckets == nil {
+		buckets = DefaultEpisodeBuckets
+	}
+	for i, bound := range buckets {
+		if bound < 1 || (i > 0 && bound <= buckets[i-1]) {
+			return nil, fmt.Errorf("episode buckets must be ascending positive numbers")
+		}
+	}
+
+	a := &Aggregator{
+		facets:  facets,
+		pivot:   opts.Pivot,
+		buckets: buckets,
+		labels:  bucketLabels(buckets),
+		counts:  map[string]map[string]int{},
+		missing: map[string]int{},
+		pivots:  map[string]map[string]int{},
+	}
+	for _, name := range facets {
+		a.counts[name] = map[string]int{}
+	}
+	return a, nil
+}
+
+// bucketLabels names the ranges between the bounds, like 1-5 and 51+
+func bucketLabels(bounds []int) []string {
+	labels := make([]string, 0, len(bounds)+1)
+	lower := 0
+	for _, upper := range bounds {
+		if upper-lower == 1 {
+			labels = append(labels, strconv.Itoa(lower))
+		} else {
+			labels = append(labels, fmt.Sprintf("%d-%d", lower, upper-1))
+		}
+		lower = upper
+	}
+	return append(labels, fmt.Sprintf("%d+", lower))
+}
+
+// Value is the bucket an episode falls in for a facet, vocabulary values
+// are counted by their canonical code when they are known. Empty means
+// the episode has no value. Negative episode counts fall in the first bucket.
+func (a *Aggregator) Value(name string, episode models.Episode) string {
+	switch name {
+	case Genre:
+		return canonical(episode.Genre, vocab.Genres.Normalize)
+	case Country:
+		return canonical(episode.Country, vocab.Country)
+	case Language:
+		return canonical(episode.Language, vocab.Language)
+	case TVChannel:
+		return strings.TrimSpace(episode.TVChannel)
+	case DRM:
+		return strconv.FormatBool(episode.DRM)
+	case EpisodeCount:
+		bucket, _ := slices.BinarySearch(a.buckets, episode.EpisodeCount+1)
+		return a.labels[bucket]
+	}
+	return ""
+}
+
+func canonical(value string, lookup func(string) (string, bool)) string {
+	if code, ok := lookup(value); ok {
+		return code
+	}
+	return strings.TrimSpace(value)
+}
+
+// Add counts an episode
+func (a *Aggregator) Add(episode models.Episode) {
+	a.total++
+	for _, name := range a.facets {
+		if value := a.Value(name, episode); value != "" {
+			a.counts[name][value]++
+		} else {
+			a.missing[name]++
+		}
+	}
+	// episodes without either value are left out of the pivot
+	outer, inner := "", ""
+	if len(a.pivot) == 2 {
+		outer, inner = a.Value(a.pivot[0], episode), a.Value(a.pivot[1], episode)
+	}
+	if outer != "" && inner != "" {
+		if a.pivots[outer] == nil {
+			a.pivots[outer] = map[string]int{}
+		}
+		a.pivots[outer][inner]++
+	}
+
+	count := episode.EpisodeCount
+	a.stats.Count++
+	a.stats.Sum += count
+	if a.stats.Min == nil || count < *a.stats.Min {
+		a.stats.Min = &count
+	}
+	if a.stats.Max == nil || count > *a.stats.Max {
+		a.stats.Max = &count
+	}
+}
+
+// Result returns the counts so far. Buckets are ordered by count, most
+// first, except episodeCount buckets which stay in range order.
+func (a *Aggregator) Result() models.FacetsResponse {
+	result := models.FacetsResponse{
+		Total:        a.total,
+		Facets:       map[string]models.Facet{},
+		EpisodeCount: a.stats,
+	}
+	if a.stats.Count > 0 {
+		avg := float64(a.stats.Sum) / float64(a.stats.Count)
+		result.EpisodeCount.Avg = &avg
+	}
+
+	for _, name := range a.facets {
+		result.Facets[name] = models.Facet{
+			Buckets: a.sortedBuckets(name, a.counts[name]),
+			Missing: a.missing[name],
+		}
+	}
+
+	if len(a.pivot) == 2 {
+		outer := map[string]int{}
+		for value, inner := range a.pivots {
+			for _, n := range inner {
+				outer[value] += n
+			}
+		}
+		pivot := &models.FacetPivot{Facets: a.pivot, Buckets: a.sortedBuckets(a.pivot[0], outer)}
+		for i := range pivot.Buckets {
+			pivot.Buckets[i].Pivot = a.sortedBuckets(a.pivot[1], a.pivots[pivot.Buckets[i].Value])
+		}
+		result.Pivot = pivot
+	}
+	return result
+}
+
+func (a *Aggregator) sortedBuckets(name string, counts map[string]int) []models.FacetBucket {
+	if name == EpisodeCount {
+		buckets := make([]models.FacetBucket, 0, len(a.labels))
+		for _, label := range a.labels {
+			buckets = append(buckets, models.FacetBucket{Value: label, Count: counts[label]})
+		}
+		return buckets
+	}
+
+	buckets := make([]models.FacetBucket, 0, len(counts))
+	for value, count := range counts {
+		buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+	}
+	slices.SortFunc(buckets, func(x, y models.FacetBucket) int {
+		if c := cmp.Compare(y.Count, x.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.Value, y.Value)
+	})
+	return buckets
+}
@@ -0,0 +1,141 @@
+package facets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/models"
+)
+
+func episodes() []models.Episode {
+	return []models.Episode{
+		{DRM: true, EpisodeCount: 3, Genre: "Reality", Country: "UK", Language: "English", TVChannel: "GEM"},
+		{DRM: true, EpisodeCount: 24, Genre: "reality", Country: "GB", Language: "en", TVChannel: "GEM"},
+		{DRM: false, EpisodeCount: 0, Genre: "Drama", Country: "AU", Language: "en", TVChannel: "Nine"},
+		{DRM: true, EpisodeCount: 60, Genre: "Drama", Country: "United Kingdom"},
+		{DRM: true, EpisodeCount: 1, Genre: "Telenovela", Country: "Narnia", Language: "es"},
+	}
+}
+
+func aggregate(t *testing.T, opts Options) models.FacetsResponse {
+	t.Helper()
+	a, err := New(opts)
+	assert.NoError(t, err)
+	for _, episode := range episodes() {
+		a.Add(episode)
+	}
+	return a.Result()
+}
+
+func TestFacets(t *testing.T) {
+	result := aggregate(t, Options{})
+
+	assert.Equal(t, 5, result.Total)
+	assert.Len(t, result.Facets, len(Names))
+	assert.Nil(t, result.Pivot)
+
+	tests := []struct {
+		facet           string
+		expected        []models.FacetBucket
+		expectedMissing int
+	}{
+		{
+			facet: Genre,
+			expected: []models.FacetBucket{
+				{Value: "drama", Count: 2}, {Value: "reality", Count: 2}, {Value: "Telenovela", Count: 1},
+			},
+		},
+		{
+			facet:    Country,
+			expected: []models.FacetBucket{{Value: "GB", Count: 3}, {Value: "AU", Count: 1}, {Value: "Narnia", Count: 1}},
+		},
+		{
+			facet:           Language,
+			expected:        []models.FacetBucket{{Value: "en", Count: 3}, {Value: "es", Count: 1}},
+			expectedMissing: 1,
+		},
+		{
+			facet:           TVChannel,
+			expected:        []models.FacetBucket{{Value: "GEM", Count: 2}, {Value: "Nine", Count: 1}},
+			expectedMissing: 2,
+		},
+		{
+			facet:    DRM,
+			expected: []models.FacetBucket{{Value: "true", Count: 4}, {Value: "false", Count: 1}},
+		},
+		{
+			facet: EpisodeCount,
+			expected: []models.FacetBucket{
+				{Value: "0", Count: 1}, {Value: "1-5", Count: 2}, {Value: "6-10", Count: 0},
+				{Value: "11-25", Count: 1}, {Value: "26-50", Count: 0}, {Value: "51+", Count: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.facet, func(t *testing.T) {
+			assert.Equal(t, tt.expected, result.Facets[tt.facet].Buckets)
+			assert.Equal(t, tt.expectedMissing, result.Facets[tt.facet].Missing)
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	result := aggregate(t, Options{Facets: []string{}})
+	assert.Empty(t, result.Facets)
+	assert.Equal(t, 5, result.EpisodeCount.Count)
+	assert.Equal(t, 88, result.EpisodeCount.Sum)
+	assert.Equal(t, 0, *result.EpisodeCount.Min)
+	assert.Equal(t, 60, *result.EpisodeCount.Max)
+	assert.InDelta(t, 17.6, *result.EpisodeCount.Avg, 1e-9)
+
+	a, err := New(Options{})
+	assert.NoError(t, err)
+	empty := a.Result()
+	assert.Equal(t, 0, empty.Total)
+	assert.Nil(t, empty.EpisodeCount.Min)
+	assert.Nil(t, empty.EpisodeCount.Avg)
+	assert.Equal(t, []models.FacetBucket{}, empty.Facets[Genre].Buckets)
+}
+
+func TestPivot(t *testing.T) {
+	result := aggregate(t, Options{Facets: []string{DRM}, Pivot: []string{Country, Genre}})
+
+	assert.Equal(t, &models.FacetPivot{
+		Facets: []string{Country, Genre},
+		Buckets: []models.FacetBucket{
+			{Value: "GB", Count: 3, Pivot: []models.FacetBucket{{Value: "reality", Count: 2}, {Value: "drama", Count: 1}}},
+			{Value: "AU", Count: 1, Pivot: []models.FacetBucket{{Value: "drama", Count: 1}}},
+			{Value: "Narnia", Count: 1, Pivot: []models.FacetBucket{{Value: "Telenovela", Count: 1}}},
+		},
+	}, result.Pivot)
+}
+
+func TestCustomBuckets(t *testing.T) {
+	result := aggregate(t, Options{Facets: []string{EpisodeCount}, EpisodeBuckets: []int{2, 50}})
+	assert.Equal(t, []models.FacetBucket{
+		{Value: "0-1", Count: 2}, {Value: "2-49", Count: 2}, {Value: "50+", Count: 1},
+	}, result.Facets[EpisodeCount].Buckets)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          Options
+		expectedError string
+	}{
+		{"Unknown facet", Options{Facets: []string{"colour"}}, "unknown facet colour, facets are [genre country language tvChannel drm episodeCount]"},
+		{"Unknown pivot facet", Options{Pivot: []string{Genre, "colour"}}, "unknown facet colour, facets are [genre country language tvChannel drm episodeCount]"},
+		{"One pivot facet", Options{Pivot: []string{Genre}}, "a pivot needs two different facets"},
+		{"Same pivot facets", Options{Pivot: []string{Genre, Genre}}, "a pivot needs two different facets"},
+		{"Descending buckets", Options{EpisodeBuckets: []int{5, 2}}, "episode buckets must be ascending positive numbers"},
+		{"Zero bucket", Options{EpisodeBuckets: []int{0}}, "episode buckets must be ascending positive numbers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			assert.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package imagecheck
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"stan.com/stantest/urlpolicy"
+)
+
+// NewClient returns the client used to fetch feed images. It always refuses
+// to connect to private addresses: the URLs come from request bodies, and
+// host names in them could otherwise resolve to loopback or cloud metadata
+// addresses. Tests fetching from a local server pass their own client to New.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if urlpolicy.IsPrivateIP(addrPort.Addr()) {
+				return fmt.Errorf("refusing to connect to private address %s", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// the client must not be steered through a proxy to internal hosts either
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// redirects are checked against the dialer guard like the first hop
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return nil
+		},
+	}
+}
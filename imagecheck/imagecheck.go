@@ -0,0 +1,257 @@
+package imagecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxProbeBytes bounds how much of an image is read to find its dimensions,
+// the header of every decoder we register fits well inside
+const maxProbeBytes = 64 << 10
+
+// maxCacheEntries bounds the result cache, expired entries are purged first
+const maxCacheEntries = 10000
+
+// Options tune a Verifier
+type Options struct {
+	// Concurrency is the number of images checked at once, at least 1
+	Concurrency int
+	// Timeout bounds each check, 0 leaves it to the client
+	Timeout time.Duration
+	// CacheTTL is how long results are reused, 0 disables the cache
+	CacheTTL time.Duration
+	// ContentTypes are the accepted media types, empty accepts any image/*
+	ContentTypes []string
+	// MinWidth and MinHeight are the smallest acceptable dimensions,
+	// 0 skips fetching the image to measure it
+	MinWidth  int
+	MinHeight int
+}
+
+// Result is the outcome of checking one image
+type Result struct {
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// Problem says why the image is unusable, empty when it is fine
+	Problem string `json:"problem,omitempty"`
+}
+
+// OK reports whether the image is reachable and acceptable
+func (r Result) OK() bool {
+	return r.Problem == ""
+}
+
+type cacheEntry struct {
+	result  Result
+	expires time.Time
+}
+
+// Verifier fetches images to check they exist and are usable
+type Verifier struct {
+	client  *http.Client
+	options Options
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// New returns a verifier using client for all requests
+func New(client *http.Client, options Options) *Verifier {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	return &Verifier{
+		client:  client,
+		options: options,
+		now:     time.Now,
+		cache:   map[string]cacheEntry{},
+	}
+}
+
+// CheckAll checks every distinct URL, at most Concurrency at a time.
+// URLs left unchecked when ctx ends are missing from the result.
+func (v *Verifier) CheckAll(ctx context.Context, urls []string) map[string]Result {
+	results := make(map[string]Result, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, v.options.Concurrency)
+
+	seen := map[string]bool{}
+	for _, url := range urls {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return results
+		}
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := v.Check(ctx, url)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			results[url] = result
+			mu.Unlock()
+		}(url)
+	}
+	wg.Wait()
+	return results
+}
+
+// Check checks a single image, using the cache when it can. The error is
+// only set when ctx ended, an unusable image is reported in the result.
+func (v *Verifier) Check(ctx context.Context, url string) (Result, error) {
+	if result, ok := v.cached(url); ok {
+		return result, nil
+	}
+
+	checkCtx := ctx
+	if v.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, v.options.Timeout)
+		defer cancel()
+	}
+
+	result := v.check(checkCtx, url)
+	// the caller gave up, this says nothing about the image
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	v.store(url, result)
+	return result, nil
+}
+
+func (v *Verifier) check(ctx context.Context, url string) Result {
+	measure := v.options.MinWidth > 0 || v.options.MinHeight > 0
+
+	method := http.MethodHead
+	if measure {
+		method = http.MethodGet
+	}
+	resp, err := v.do(ctx, method, url)
+	// some image hosts don't implement HEAD
+	if err == nil && method == http.MethodHead &&
+		(resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = v.do(ctx, http.MethodGet, url)
+	}
+	if err != nil {
+		return Result{Problem: "unreachable: " + requestError(err)}
+	}
+	defer resp.Body.Close()
+
+	result := Result{Status: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Problem = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return result
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	result.ContentType = mediaType
+	if !v.acceptedType(mediaType) {
+		result.Problem = fmt.Sprintf("unexpected content type %q", mediaType)
+		return result
+	}
+
+	if !measure || resp.Request.Method != http.MethodGet {
+		return result
+	}
+	config, _, err := image.DecodeConfig(io.LimitReader(resp.Body, maxProbeBytes))
+	if err != nil {
+		result.Problem = "dimensions unreadable: " + err.Error()
+		return result
+	}
+	result.Width, result.Height = config.Width, config.Height
+	if result.Width < v.options.MinWidth || result.Height < v.options.MinHeight {
+		result.Problem = fmt.Sprintf("%dx%d is smaller than %dx%d", result.Width, result.Height, v.options.MinWidth, v.options.MinHeight)
+	}
+	return result
+}
+
+func (v *Verifier) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+	return v.client.Do(req)
+}
+
+// acceptedType reports whether the media type is an allowed image type
+func (v *Verifier) acceptedType(mediaType string) bool {
+	if len(v.options.ContentTypes) == 0 {
+		return strings.HasPrefix(mediaType, "image/")
+	}
+	return slices.ContainsFunc(v.options.ContentTypes, func(allowed string) bool {
+		return strings.EqualFold(allowed, mediaType)
+	})
+}
+
+// requestError shortens client errors to something fit for a report
+func requestError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	var urlErr interface{ Unwrap() error }
+	if errors.As(err, &urlErr) && urlErr.Unwrap() != nil {
+		return urlErr.Unwrap().Error()
+	}
+	return err.Error()
+}
+
+func (v *Verifier) cached(url string) (Result, bool) {
+	if v.options.CacheTTL <= 0 {
+		return Result{}, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry, ok := v.cache[url]
+	if !ok || v.now().After(entry.expires) {
+		return Result{}, false
+	}
+	return entry.result, true
+}
+
+func (v *Verifier) store(url string, result Result) {
+	if v.options.CacheTTL <= 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := v.now()
+	if len(v.cache) >= maxCacheEntries {
+		for key, entry := range v.cache {
+			if now.After(entry.expires) {
+				delete(v.cache, key)
+			}
+		}
+		// still full of live entries, start over rather than grow
+		if len(v.cache) >= maxCacheEntries {
+			clear(v.cache)
+		}
+	}
+	v.cache[url] = cacheEntry{result: result, expires: now.Add(v.options.CacheTTL)}
+}
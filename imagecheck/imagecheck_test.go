@@ -0,0 +1,169 @@
+package imagecheck
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// imageServer serves a 640x360 png at /ok.png, a png without HEAD support
+// at /nohead.png, html at /page and 404 for anything else
+func imageServer(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 360))))
+	pngData := buf.Bytes()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+		switch r.URL.Path {
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		case "/nohead.png":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestCheck(t *testing.T) {
+	server := imageServer(t, nil)
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		options         Options
+		path            string
+		expectedProblem string
+		expectedWidth   int
+	}{
+		{name: "Reachable image", path: "/ok.png"},
+		{name: "Missing image", path: "/missing.png", expectedProblem: "unexpected status 404"},
+		{name: "Not an image", path: "/page", expectedProblem: `unexpected content type "text/html"`},
+		{name: "Content type not accepted", options: Options{ContentTypes: []string{"image/jpeg"}}, path: "/ok.png", expectedProblem: `unexpected content type "image/png"`},
+		{name: "HEAD falls back to GET", path: "/nohead.png"},
+		{name: "Large enough", options: Options{MinWidth: 320, MinHeight: 180}, path: "/ok.png", expectedWidth: 640},
+		{name: "Too small", options: Options{MinWidth: 1280}, path: "/ok.png", expectedProblem: "640x360 is smaller than 1280x0", expectedWidth: 640},
+		{name: "Timed out", options: Options{Timeout: 20 * time.Millisecond}, path: "/slow.png", expectedProblem: "unreachable: timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := New(server.Client(), tt.options)
+			result, err := verifier.Check(context.Background(), server.URL+tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedProblem, result.Problem)
+			assert.Equal(t, tt.expectedProblem == "", result.OK())
+			assert.Equal(t, tt.expectedWidth, result.Width)
+		})
+	}
+}
+
+func TestCheckUnreachableHost(t *testing.T) {
+	server := imageServer(t, nil)
+	url := server.URL + "/ok.png"
+	server.Close()
+
+	result, err := New(server.Client(), Options{}).Check(context.Background(), url)
+	assert.NoError(t, err)
+	assert.False(t, result.OK())
+	assert.Contains(t, result.Problem, "unreachable")
+}
+
+func TestCheckUsesCache(t *testing.T) {
+	var hits atomic.Int32
+	server := imageServer(t, &hits)
+	defer server.Close()
+
+	clock := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	verifier := New(server.Client(), Options{CacheTTL: time.Minute})
+	verifier.now = func() time.Time { return clock }
+
+	for i := 0; i < 3; i++ {
+		result, err := verifier.Check(context.Background(), server.URL+"/missing.png")
+		assert.NoError(t, err)
+		assert.Equal(t, 404, result.Status)
+	}
+	assert.Equal(t, int32(1), hits.Load())
+
+	clock = clock.Add(2 * time.Minute)
+	_, err := verifier.Check(context.Background(), server.URL+"/missing.png")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestCheckAllLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "image/jpeg")
+	}))
+	defer server.Close()
+
+	var urls []string
+	for _, path := range []string{"/a.jpg", "/b.jpg", "/c.jpg", "/d.jpg", "/e.jpg", "/f.jpg", "/a.jpg"} {
+		urls = append(urls, server.URL+path)
+	}
+	results := New(server.Client(), Options{Concurrency: 2}).CheckAll(context.Background(), urls)
+
+	assert.Len(t, results, 6)
+	for _, result := range results {
+		assert.True(t, result.OK())
+	}
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
+
+func TestCheckAllStopsWithContext(t *testing.T) {
+	server := imageServer(t, nil)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := New(server.Client(), Options{}).CheckAll(ctx, []string{server.URL + "/ok.png"})
+	assert.Empty(t, results)
+}
+
+func TestNewClientBlocksPrivateAddresses(t *testing.T) {
+	server := imageServer(t, nil)
+	defer server.Close()
+
+	result, err := New(NewClient(time.Second), Options{}).Check(context.Background(), server.URL+"/ok.png")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Problem, "refusing to connect to private address 127.0.0.1")
+
+	// the server itself is reachable, only the guard refused it
+	result, err = New(server.Client(), Options{}).Check(context.Background(), server.URL+"/ok.png")
+	assert.NoError(t, err)
+	assert.True(t, result.OK())
+}
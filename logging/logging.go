@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// RequestIDKey is the echo context key the request id is stored under
+const RequestIDKey = "request_id"
+
+// baseHeader is the JSON header shared by all application loggers.
+// gommon merges the message (or the fields of a *j call) into it.
+const baseHeader = `{"time":"${time_rfc3339}","level":"${level}","file":"${short_file}","line":"${line}"`
+
+// Header builds a JSON log header with the given static fields appended,
+// fields are passed as key/value pairs
+func Header(fields ...string) string {
+	var sb strings.Builder
+	sb.WriteString(baseHeader)
+	for i := 0; i+1 < len(fields); i += 2 {
+		sb.WriteByte(',')
+		sb.WriteString(quote(fields[i]))
+		sb.WriteByte(':')
+		sb.WriteString(quote(fields[i+1]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// NewRequestLogger creates a logger which shares output and level with base
+// but stamps every line with the request id and the matched route
+func NewRequestLogger(base echo.Logger, requestID, route string) echo.Logger {
+	l := log.New("-")
+	l.SetOutput(base.Output())
+	l.SetLevel(base.Level())
+	l.SetHeader(Header(RequestIDKey, requestID, "route", route))
+	return l
+}
+
+// RequestID returns the id of the current request, or empty when the
+// request did not pass through the request id middleware
+func RequestID(c echo.Context) string {
+	if id, ok := c.Get(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// quote JSON encodes a header value, "$" is escaped as well so values can
+// never be picked up as template tags by the header template
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return strings.ReplaceAll(string(b), "$", `\u0024`)
+}
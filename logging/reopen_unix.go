@@ -0,0 +1,29 @@
+//go:build !windows
+
+package logging
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReopenOnSignal reopens the sinks whenever the process receives SIGUSR1,
+// which is what external logrotate setups send after moving files away
+func ReopenOnSignal(logger echo.Logger, sinks ...Sink) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+
+	go func() {
+		for range usr1 {
+			for _, sink := range sinks {
+				if err := sink.Reopen(); err != nil {
+					logger.Errorf("failed to reopen log file: %s", err.Error())
+				}
+			}
+			logger.Info("log files reopened")
+		}
+	}()
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package logging
+
+import "github.com/labstack/echo/v4"
+
+// ReopenOnSignal is a no-op on windows, there is no SIGUSR1
+func ReopenOnSignal(logger echo.Logger, sinks ...Sink) {}
@@ -0,0 +1,251 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stan.com/stantest/config"
+)
+
+// backupTimeFormat is embedded in rotated file names, e.g. app-20240102T150405.000.log
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile is a log file which rotates itself by size and age.
+// Rotated files are optionally gzipped and pruned by count and age.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	cfg      config.LogSink
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// housekeeping of rotated files runs in the background, one at a time
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+
+	// now is swappable for tests
+	now func() time.Time
+}
+
+// NewRotatingFile opens (or creates) the log file at path
+func NewRotatingFile(path string, cfg config.LogSink) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, cfg: cfg, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends p to the file, rotating first when size or age limits are hit
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.shouldRotate(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and reopens the file at the same path, so external
+// logrotate can move the file away and signal us to start a new one
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.close(); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// Close closes the file and waits for background housekeeping to finish
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	err := r.close()
+	r.mu.Unlock()
+
+	r.millWg.Wait()
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.now()
+	return nil
+}
+
+func (r *RotatingFile) close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) shouldRotate(incoming int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.cfg.MaxSizeMB > 0 && r.size+int64(incoming) > int64(r.cfg.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return r.cfg.RotateInterval > 0 && r.now().Sub(r.openedAt) >= r.cfg.RotateInterval
+}
+
+// rotate moves the current file aside and starts a new one
+func (r *RotatingFile) rotate() error {
+	if err := r.close(); err != nil {
+		return err
+	}
+	now := r.now()
+	if err := os.Rename(r.path, r.backupName(now)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.millWg.Add(1)
+	go func() {
+		defer r.millWg.Done()
+		r.mill(now)
+	}()
+	return nil
+}
+
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type backup struct {
+	path string
+	at   time.Time
+}
+
+// mill compresses rotated files and removes the ones out of retention
+func (r *RotatingFile) mill(now time.Time) {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	backups := r.backups()
+
+	// newest first, anything past MaxBackups or older than MaxAge goes
+	var keep []backup
+	cutoff := now.Add(-r.cfg.MaxAge)
+	for i, b := range backups {
+		if (r.cfg.MaxBackups > 0 && i >= r.cfg.MaxBackups) || (r.cfg.MaxAge > 0 && b.at.Before(cutoff)) {
+			os.Remove(b.path)
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if !r.cfg.Compress {
+		return
+	}
+	for _, b := range keep {
+		if !strings.HasSuffix(b.path, ".gz") {
+			compressFile(b.path)
+		}
+	}
+}
+
+// backups lists rotated files sorted newest first
+func (r *RotatingFile) backups() []backup {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		at, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp, ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), at: at})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+	return backups
+}
+
+// compressFile gzips src next to itself and removes the original
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(src + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(src + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
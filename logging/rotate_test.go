@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stantest.log")
+
+	r, err := NewRotatingFile(path, config.LogSink{MaxSizeMB: 1, MaxBackups: 2, Compress: true})
+	assert.NoError(t, err)
+
+	// every write fills most of a megabyte, so each one after the first rotates
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	line := []byte(strings.Repeat("x", 700*1024) + "\n")
+	for i := 0; i < 4; i++ {
+		_, err := r.Write(line)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, r.Close())
+
+	names := listDir(t, dir)
+	assert.Contains(t, names, "stantest.log")
+	// 3 rotations happened, only the 2 newest backups survive, all compressed
+	assert.Len(t, names, 3)
+	for _, name := range names {
+		if name != "stantest.log" {
+			assert.True(t, strings.HasPrefix(name, "stantest-"), name)
+			assert.True(t, strings.HasSuffix(name, ".log.gz"), name)
+		}
+	}
+}
+
+func TestRotatingFileByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	r, err := NewRotatingFile(path, config.LogSink{RotateInterval: time.Hour, MaxAge: 48 * time.Hour})
+	assert.NoError(t, err)
+
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+	r.openedAt = clock
+
+	// a stale backup outside retention must be pruned on rotation
+	stale := filepath.Join(dir, "access-"+clock.Add(-72*time.Hour).Format(backupTimeFormat)+".log")
+	assert.NoError(t, os.WriteFile(stale, []byte("old\n"), 0644))
+
+	_, err = r.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	clock = clock.Add(2 * time.Hour)
+	_, err = r.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	names := listDir(t, dir)
+	assert.NotContains(t, names, filepath.Base(stale))
+	assert.Len(t, names, 2)
+
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(current))
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stantest.log")
+
+	r, err := NewRotatingFile(path, config.LogSink{})
+	assert.NoError(t, err)
+	_, err = r.Write([]byte("before\n"))
+	assert.NoError(t, err)
+
+	// simulate external logrotate moving the file away
+	moved := filepath.Join(dir, "stantest.log.1")
+	assert.NoError(t, os.Rename(path, moved))
+	assert.NoError(t, r.Reopen())
+	_, err = r.Write([]byte("after\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	old, err := os.ReadFile(moved)
+	assert.NoError(t, err)
+	assert.Equal(t, "before\n", string(old))
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "after\n", string(current))
+}
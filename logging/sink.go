@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"io"
+	"os"
+
+	"stan.com/stantest/config"
+)
+
+// Sink is a log destination that can be reopened, e.g. after an external
+// logrotate moved the file away, and closed on shutdown
+type Sink interface {
+	io.Writer
+	Reopen() error
+	Close() error
+}
+
+// OpenSink opens the destination described by cfg
+func OpenSink(cfg config.LogSink) (Sink, error) {
+	if cfg.Output == "" || cfg.Output == config.LOG_OUTPUT_STDOUT {
+		return stdoutSink{}, nil
+	}
+	return NewRotatingFile(cfg.Output, cfg)
+}
+
+// stdoutSink writes to the console, there is nothing to reopen or close
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdoutSink) Reopen() error               { return nil }
+func (stdoutSink) Close() error                { return nil }
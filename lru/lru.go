@@ -0,0 +1,124 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Options bound what a Cache keeps
+type Options struct {
+	// MaxEntries caps the number of entries, 0 means no cap
+	MaxEntries int
+	// MaxBytes caps the total size of the entries as given to Add, 0
+	// means no cap. Larger entries aren't kept at all.
+	MaxBytes int64
+	// TTL is how long an entry is served, 0 keeps entries until evicted
+	TTL time.Duration
+}
+
+// Stats are counters since the cache was created and its current size
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Expired   int64 `json:"expired"`
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// Cache is a least recently used cache safe for concurrent use
+type Cache[V any] struct {
+	opts Options
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+	stats Stats
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	size    int64
+	expires time.Time
+}
+
+// New returns an empty cache
+func New[V any](opts Options) *Cache[V] {
+	return &Cache[V]{opts: opts, now: time.Now, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the value for key and marks it recently used
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if ok {
+		e := element.Value.(*entry[V])
+		if e.expires.IsZero() || c.now().Before(e.expires) {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.remove(element)
+		c.stats.Expired++
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Add keeps value for key, evicting the least recently used entries to
+// stay within the limits. It reports whether the value was kept.
+func (c *Cache[V]) Add(key string, value V, size int64) bool {
+	if c.opts.MaxBytes > 0 && size > c.opts.MaxBytes {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+	e := &entry[V]{key: key, value: value, size: size}
+	if c.opts.TTL > 0 {
+		e.expires = c.now().Add(c.opts.TTL)
+	}
+	c.items[key] = c.order.PushFront(e)
+	c.stats.Entries++
+	c.stats.Bytes += size
+
+	for (c.opts.MaxEntries > 0 && c.stats.Entries > c.opts.MaxEntries) ||
+		(c.opts.MaxBytes > 0 && c.stats.Bytes > c.opts.MaxBytes) {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	return true
+}
+
+// Purge drops every entry, the counters are kept
+func (c *Cache[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = map[string]*list.Element{}
+	c.stats.Entries, c.stats.Bytes = 0, 0
+}
+
+// Stats returns the counters and current size
+func (c *Cache[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *Cache[V]) remove(element *list.Element) {
+	e := c.order.Remove(element).(*entry[V])
+	delete(c.items, e.key)
+	c.stats.Entries--
+	c.stats.Bytes -= e.size
+}
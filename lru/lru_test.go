@@ -0,0 +1,96 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAndAdd(t *testing.T) {
+	c := New[string](Options{})
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.True(t, c.Add("a", "one", 3))
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "one", value)
+
+	// replacing keeps a single entry
+	c.Add("a", "uno", 3)
+	value, _ = c.Get("a")
+	assert.Equal(t, "uno", value)
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Entries: 1, Bytes: 3}, c.Stats())
+}
+
+func TestEviction(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		sizes    []int64
+		expected []string
+	}{
+		{"Entries", Options{MaxEntries: 2}, []int64{1, 1, 1}, []string{"b", "c"}},
+		{"Bytes", Options{MaxBytes: 10}, []int64{4, 4, 4}, []string{"b", "c"}},
+		{"Several for a large entry", Options{MaxBytes: 10}, []int64{3, 3, 9}, []string{"c"}},
+		{"Too large to keep", Options{MaxBytes: 10}, []int64{3, 3, 11}, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[int](tt.opts)
+			for i, key := range []string{"a", "b", "c"} {
+				c.Add(key, i, tt.sizes[i])
+			}
+			var kept []string
+			for _, key := range []string{"a", "b", "c"} {
+				if _, ok := c.Get(key); ok {
+					kept = append(kept, key)
+				}
+			}
+			assert.Equal(t, tt.expected, kept)
+		})
+	}
+}
+
+func TestLeastRecentlyUsedGoesFirst(t *testing.T) {
+	c := New[int](Options{MaxEntries: 2})
+	c.Add("a", 1, 1)
+	c.Add("b", 2, 1)
+	c.Get("a")
+	c.Add("c", 3, 1)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), c.Stats().Evictions)
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[int](Options{TTL: time.Minute})
+	c.now = func() time.Time { return now }
+	c.Add("a", 1, 1)
+
+	now = now.Add(59 * time.Second)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Expired: 1}, c.Stats())
+}
+
+func TestPurge(t *testing.T) {
+	c := New[int](Options{})
+	c.Add("a", 1, 5)
+	c.Get("a")
+	c.Purge()
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1}, c.Stats())
+}
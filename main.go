@@ -2,83 +2,203 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
+	"golang.org/x/net/http2"
+	"stan.com/stantest/admin"
+	"stan.com/stantest/catalogue"
 	"stan.com/stantest/config"
+	"stan.com/stantest/controllers"
+	"stan.com/stantest/logging"
+	"stan.com/stantest/middlewares"
 	"stan.com/stantest/routes"
+	"stan.com/stantest/rules"
+	"stan.com/stantest/tlsconfig"
+	"stan.com/stantest/vocab"
 )
 
 func main() {
 	// create a new echo instance
 	e := echo.New()
+	// every error response carries the request id
+	e.HTTPErrorHandler = controllers.HTTPErrorHandler
 
-	// set logging level if not existing with default debug level
-	logLevelStr := os.Getenv("STAN_EPISODE_SERVER_LOG_LEVEL")
-	logLevel := config.LOG_LEVEL_DEBUG
-	if logLevelStr == "" {
-		logLevel = log.Lvl(config.LOG_LEVEL_DEBUG)
+	// load config from system environment, defaults apply for anything not set
+	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		e.Logger.Fatal("invalid configuration:", err)
 	}
-	e.Logger.SetLevel(logLevel)
-	e.Logger.SetHeader("${time_rfc3339} ${level} ${short_file}:${line}")
+	controllers.Configure(cfg)
+	// response cache hits and misses, served by the admin server under /debug/vars
+	expvar.Publish("response_cache", expvar.Func(controllers.CacheStats))
 
-	// create or open the log file
-	//logFile, err := os.OpenFile("stantest.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	//if err != nil {
-	//	e.Logger.Fatal("failed to open log file:", err)
-	//}
-	//defer logFile.Close() // ensure log file closed correctly
+	// set logging level, default debug level
+	e.Logger.SetLevel(cfg.LogLevel)
+	// structured JSON logs, request scoped loggers extend this header
+	e.Logger.SetHeader(logging.Header())
 
-	// bind the logger with our log file
+	// open application and access log destinations
 	// use console as default
-	//e.Logger.SetOutput(logFile)
+	appLog, err := logging.OpenSink(cfg.AppLog)
+	if err != nil {
+		e.Logger.Fatal("failed to open log file:", err)
+	}
+	// every open sink is listed once, even when both streams share it
+	sinks := []logging.Sink{appLog}
+	closeLogs := func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}
+	defer closeLogs() // ensure log files closed correctly
+	// Logger.Fatal exits without running deferred calls, fatal closes the
+	// log files first so nothing written to them is lost
+	fatal := func(args ...any) {
+		e.Logger.Error(args...)
+		closeLogs()
+		os.Exit(1)
+	}
+
+	// both streams may share one destination, never open a file twice
+	accessLog := appLog
+	if cfg.AccessLog.Output != cfg.AppLog.Output {
+		accessLog, err = logging.OpenSink(cfg.AccessLog)
+		if err != nil {
+			fatal("failed to open access log file:", err)
+		}
+		sinks = append(sinks, accessLog)
+	}
+
+	// bind the logger with our log file
+	e.Logger.SetOutput(appLog)
+	// external logrotate sends SIGUSR1 after moving the files
+	logging.ReopenOnSignal(e.Logger, sinks...)
+
+	// consumers can bring their own genre taxonomy
+	if cfg.Validation.GenreTaxonomyFile != "" {
+		taxonomy, err := vocab.LoadTaxonomy(cfg.Validation.GenreTaxonomyFile)
+		if err != nil {
+			fatal("failed to load genre taxonomy:", err)
+		}
+		vocab.Genres = taxonomy
+	}
+
+	// declared validation rules, selected per request by profile
+	if cfg.Validation.RulesFile != "" {
+		ruleSet, err := rules.Load(cfg.Validation.RulesFile)
+		if err != nil {
+			fatal("failed to load validation rules:", err)
+		}
+		if _, ok := ruleSet.Profile(cfg.Validation.RulesProfile); cfg.Validation.RulesProfile != "" && !ok {
+			fatal("unknown validation rules profile:", cfg.Validation.RulesProfile)
+		}
+		rules.Profiles = ruleSet
+	}
+
+	// catalogue queried through GET /api/v1/shows, until one is uploaded
+	if cfg.Catalogue.File != "" {
+		snapshot, err := catalogue.Current.LoadFile(cfg.Catalogue.File, controllers.CheckCatalogue)
+		if err != nil {
+			fatal("failed to load catalogue:", err)
+		}
+		e.Logger.Infof("loaded catalogue %s with %d shows", snapshot.Version, len(snapshot.Episodes))
+	}
 
 	// add some default middlewares
-	//e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-	//	Output: logFile,
-	//}))
-	e.Use(middleware.Logger())
+	inFlight := middlewares.NewInFlight()
+	e.Use(inFlight.Middleware())
+	e.Use(middlewares.RequestID())
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Output: accessLog,
+	}))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	if cfg.Compression.Enabled {
+		e.Use(middlewares.Compress(cfg.Compression.MinSize))
+	}
+	// processing deadline, handlers watch it through the request context
+	if cfg.Timeouts.Request > 0 {
+		e.Use(middleware.ContextTimeout(cfg.Timeouts.Request))
+	}
 
 	// bind routes
-	routes.SetupRoutes(e)
+	routes.SetupRoutes(e, cfg)
+
+	e.Server.Addr = ":" + cfg.Port
+	e.Server.ReadHeaderTimeout = cfg.Timeouts.ReadHeader
+	e.Server.ReadTimeout = cfg.Timeouts.Read
+	e.Server.WriteTimeout = cfg.Timeouts.Write
+	e.Server.IdleTimeout = cfg.Timeouts.Idle
 
-	// get API service port from system environment
-	// if not setting , use default one
-	port := os.Getenv("STAN_EPISODE_SERVER_PORT")
-	if port == "" {
-		port = config.DEFAULT_PORT
+	// serve HTTPS with HTTP/2 when a certificate is configured
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if cfg.TLS.Enabled() {
+		tlsConfig, reloader, err := tlsconfig.Build(cfg.TLS)
+		if err != nil {
+			fatal("failed to set up TLS:", err)
+		}
+		e.Server.TLSConfig = tlsConfig
+		if err := http2.ConfigureServer(e.Server, &http2.Server{}); err != nil {
+			fatal("failed to enable HTTP/2:", err)
+		}
+		// pick up renewed certificates without dropping connections
+		go reloader.Watch(watchCtx, cfg.TLS.ReloadInterval, e.Logger)
 	}
 
 	// start my server
 	go func() {
-		e.Logger.Infof("starting server on port %s", port)
-		if err := e.Start(":" + port); err != nil {
+		e.Logger.Infof("starting server on port %s", cfg.Port)
+		if err := e.StartServer(e.Server); err != nil {
 			e.Logger.Info("shutting down the server")
 		}
 	}()
 
+	// private admin listener for profiling, off unless explicitly enabled
+	var adminServer *echo.Echo
+	if cfg.Admin.Enabled {
+		if cfg.Admin.Username == "" || cfg.Admin.Password == "" {
+			fatal("admin listener requires STAN_EPISODE_SERVER_ADMIN_USERNAME and STAN_EPISODE_SERVER_ADMIN_PASSWORD")
+		}
+		adminServer = admin.New(cfg, e.Logger)
+		go func() {
+			e.Logger.Infof("starting admin server on %s", cfg.Admin.Addr)
+			if err := adminServer.Start(cfg.Admin.Addr); err != nil && err != http.ErrServerClosed {
+				e.Logger.Errorf("admin server stopped: %s", err.Error())
+			}
+		}()
+	}
+
 	// gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	e.Logger.Info("received shutdown signal")
+	pending := inFlight.Count()
+	e.Logger.Infof("received shutdown signal, %d requests in flight", pending)
 
 	// give some time to exit or shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.ShutdownGrace)
 	defer cancel()
 
+	// admin requests are never worth waiting for
+	if adminServer != nil {
+		adminServer.Close()
+	}
+
 	// shutdown server safely now
 	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Fatal("server forced to shutdown:", err)
+		aborted := inFlight.Count()
+		e.Logger.Errorf("drained %d requests, aborting %d still in flight", max(pending-aborted, 0), aborted)
+		e.Close()
+		fatal("server forced to shutdown:", err)
 	}
 
-	e.Logger.Info("server gracefully stopped")
+	e.Logger.Infof("server gracefully stopped, drained %d requests", pending)
 }
@@ -0,0 +1,261 @@
+package middlewares
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+// encodings the server can send, in the order it prefers them when the
+// client weighs several equally
+var encodings = []string{"zstd", "gzip", "deflate"}
+
+// encoder is the part of the gzip, zlib and zstd writers compression needs
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders are pooled per encoding, building one allocates its whole window
+var encoders = map[string]*sync.Pool{
+	"zstd": {New: func() any {
+		// RFC 9659 caps the window at 8MB so every client can decode it
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(1<<20))
+		return zw
+	}},
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	// HTTP deflate is the zlib format, not a raw deflate stream
+	"deflate": {New: func() any {
+		return zlib.NewWriter(nil)
+	}},
+}
+
+// Compress compresses response bodies of at least minSize bytes with the
+// encoding the client weighs highest in Accept-Encoding, zstd, gzip or
+// deflate. Smaller responses, responses without a body and ones the
+// handler encoded itself are sent as they are. A strong ETag is made weak
+// on a compressed response, the bytes differ but the content is the same.
+func Compress(minSize int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+
+			encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding))
+			if encoding == "" {
+				return next(c)
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: res.Writer,
+				encoding:       encoding,
+				minSize:        minSize,
+				status:         http.StatusOK,
+			}
+			res.Writer = cw
+			defer func() {
+				// the error handler writes to the plain writer once this returns
+				res.Writer = cw.ResponseWriter
+			}()
+
+			err := next(c)
+			if closeErr := cw.close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+			return err
+		}
+	}
+}
+
+// negotiateEncoding picks the encoding to send for an Accept-Encoding header,
+// empty means the body goes out as it is
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		switch name {
+		case "*":
+			wildcard = q
+		case "x-gzip":
+			weights["gzip"] = q
+		default:
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds the start of the body back until it knows whether
+// the response is large enough to compress
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	// wrote is set once the handler wrote a header or body
+	wrote bool
+	buf   []byte
+	enc   encoder
+	// decided is set once headers went out, compressing or not
+	decided bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	w.status = code
+	w.wrote = true
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	if !w.compressible() {
+		w.start(false)
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what is buffered, a streamed response is compressed since
+// its final size can't be known yet
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(w.compressible() && len(w.buf) > 0)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("response writer does not support hijacking")
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed at all
+func (w *compressWriter) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get(echo.HeaderContentEncoding) != "" {
+		return false
+	}
+	contentType := header.Get(echo.HeaderContentType)
+	for _, prefix := range []string{"image/", "audio/", "video/", "application/zip", "application/gzip", "application/zstd"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers and whatever is buffered
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress {
+		header.Set(echo.HeaderContentEncoding, w.encoding)
+		header.Del(echo.HeaderContentLength)
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.enc = encoders[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close ends the response, a body still buffered was too small to compress.
+// Nothing is sent for a handler which wrote nothing, its error is answered
+// by the error handler.
+func (w *compressWriter) close() error {
+	if !w.decided {
+		if !w.wrote {
+			return nil
+		}
+		return w.start(false)
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	// a closed encoder still points at this response until the next Reset
+	w.enc.Reset(nil)
+	encoders[w.encoding].Put(w.enc)
+	w.enc = nil
+	return err
+}
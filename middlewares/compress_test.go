@@ -0,0 +1,259 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"Nothing accepted", "", ""},
+		{"Only identity", "identity", ""},
+		{"Gzip", "gzip", "gzip"},
+		{"Legacy gzip name", "x-gzip", "gzip"},
+		{"Deflate", "deflate", "deflate"},
+		{"Server prefers zstd on a tie", "gzip, deflate, zstd", "zstd"},
+		{"Client weights win", "zstd;q=0.5, gzip", "gzip"},
+		{"Refused encoding", "gzip;q=0, deflate", "deflate"},
+		{"Wildcard", "*", "zstd"},
+		{"Wildcard with exclusions", "*, zstd;q=0", "gzip"},
+		{"Unsupported only", "br, compress", ""},
+		{"Case and spacing", " GZIP ; Q=0.8 ", "gzip"},
+		{"Invalid weight refuses", "zstd;q=2, gzip;q=0.1", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateEncoding(tt.header))
+		})
+	}
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case "zstd":
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(bytes.NewReader(body))
+		if err == nil {
+			defer zr.Close()
+		}
+		r = zr
+	default:
+		return string(body)
+	}
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	decoded, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(decoded)
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"payload": "` + strings.Repeat("stan ", 400) + `"}`
+	small := `{"ok": true}`
+
+	tests := []struct {
+		name             string
+		accept           string
+		handler          echo.HandlerFunc
+		expectedCode     int
+		expectedEncoding string
+		expectedBody     string
+		expectedETag     string
+	}{
+		{
+			name:   "Large body is compressed with zstd",
+			accept: "gzip, zstd",
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set("ETag", `"abc"`)
+				return c.JSONBlob(http.StatusOK, []byte(large))
+			},
+			expectedCode:     http.StatusOK,
+			expectedEncoding: "zstd",
+			expectedBody:     large,
+			expectedETag:     `W/"abc"`,
+		},
+		{
+			name:   "Gzip",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				return c.JSONBlob(http.StatusOK, []byte(large))
+			},
+			expectedCode:     http.StatusOK,
+			expectedEncoding: "gzip",
+			expectedBody:     large,
+		},
+		{
+			name:   "Deflate is zlib",
+			accept: "deflate",
+			handler: func(c echo.Context) error {
+				return c.JSONBlob(http.StatusOK, []byte(large))
+			},
+			expectedCode:     http.StatusOK,
+			expectedEncoding: "deflate",
+			expectedBody:     large,
+		},
+		{
+			name:   "Written in pieces",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				for _, piece := range strings.SplitAfter(large, " ") {
+					if _, err := c.Response().Write([]byte(piece)); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			expectedCode:     http.StatusOK,
+			expectedEncoding: "gzip",
+			expectedBody:     large,
+		},
+		{
+			name:   "Small body is sent as it is",
+			accept: "gzip, zstd",
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set("ETag", `"abc"`)
+				return c.JSONBlob(http.StatusOK, []byte(small))
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: small,
+			expectedETag: `"abc"`,
+		},
+		{
+			name:   "Client accepts no encoding",
+			accept: "",
+			handler: func(c echo.Context) error {
+				return c.JSONBlob(http.StatusOK, []byte(large))
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: large,
+		},
+		{
+			name:   "Not modified",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set("ETag", `"abc"`)
+				return c.NoContent(http.StatusNotModified)
+			},
+			expectedCode: http.StatusNotModified,
+			expectedETag: `"abc"`,
+		},
+		{
+			name:   "Already encoded",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set(echo.HeaderContentEncoding, "br")
+				return c.Blob(http.StatusOK, "application/octet-stream", []byte(large))
+			},
+			expectedCode:     http.StatusOK,
+			expectedEncoding: "br",
+			expectedBody:     large,
+		},
+		{
+			name:   "Images are left alone",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				return c.Blob(http.StatusOK, "image/png", []byte(large))
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: large,
+		},
+		{
+			name:   "Errors go through the error handler",
+			accept: "gzip",
+			handler: func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusTeapot, "short")
+			},
+			expectedCode: http.StatusTeapot,
+			expectedBody: `{"message":"short"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Compress(1024))
+			e.GET("/api/v1/shows", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/shows", nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAcceptEncoding, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.Equal(t, tt.expectedEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+			assert.Equal(t, tt.expectedETag, rec.Header().Get("ETag"))
+			encoding := tt.expectedEncoding
+			if encoding == "br" {
+				encoding = ""
+			}
+			assert.Equal(t, tt.expectedBody, decode(t, encoding, rec.Body.Bytes()))
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	e := echo.New()
+	e.Use(Compress(1024))
+	e.GET("/api/v1/stream", func(c echo.Context) error {
+		c.Response().WriteHeader(http.StatusOK)
+		if _, err := c.Response().Write([]byte("first")); err != nil {
+			return err
+		}
+		c.Response().Flush()
+		_, err := c.Response().Write([]byte(" second"))
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, "first second", decode(t, "gzip", rec.Body.Bytes()))
+}
+
+func TestCompressReusesEncoders(t *testing.T) {
+	e := echo.New()
+	e.Use(Compress(0))
+	e.GET("/api/v1/shows", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.QueryParam("n"))
+	})
+
+	for _, encoding := range []string{"zstd", "gzip", "deflate"} {
+		for _, n := range []string{"one", "two", "three"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/shows?n="+n, nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, encoding)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, n, decode(t, encoding, rec.Body.Bytes()), encoding)
+		}
+	}
+}
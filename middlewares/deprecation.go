@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Deprecation announces that an API version is on its way out. It sends
+// the RFC 9745 Deprecation header from deprecated on, the RFC 8594 Sunset
+// header when a sunset is set and a Link to the migration notes. Zero
+// times send nothing.
+func Deprecation(deprecated, sunset time.Time, link string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			if !deprecated.IsZero() {
+				header.Set("Deprecation", "@"+strconv.FormatInt(deprecated.Unix(), 10))
+				if link != "" {
+					header.Add("Link", "<"+link+`>; rel="deprecation"; type="text/html"`)
+				}
+			}
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			return next(c)
+		}
+	}
+}
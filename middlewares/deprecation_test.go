@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecation(t *testing.T) {
+	deprecated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2025, 12, 31, 23, 59, 59, 0, time.FixedZone("AEDT", 11*60*60))
+
+	tests := []struct {
+		name               string
+		deprecated         time.Time
+		sunset             time.Time
+		link               string
+		expectedDeprecated string
+		expectedSunset     string
+		expectedLink       string
+	}{
+		{name: "Not deprecated"},
+		{
+			name:               "Deprecated with a sunset",
+			deprecated:         deprecated,
+			sunset:             sunset,
+			link:               "https://example.com/docs/v2-migration",
+			expectedDeprecated: "@1735689600",
+			expectedSunset:     "Wed, 31 Dec 2025 12:59:59 GMT",
+			expectedLink:       `<https://example.com/docs/v2-migration>; rel="deprecation"; type="text/html"`,
+		},
+		{
+			name:               "Deprecated without a sunset",
+			deprecated:         deprecated,
+			expectedDeprecated: "@1735689600",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Deprecation(tt.deprecated, tt.sunset, tt.link))
+			e.GET("/api/v1/health", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expectedDeprecated, rec.Header().Get("Deprecation"))
+			assert.Equal(t, tt.expectedSunset, rec.Header().Get("Sunset"))
+			assert.Equal(t, tt.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
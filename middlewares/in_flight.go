@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InFlight counts requests currently being handled,
+// shutdown uses it to report how many requests were drained or aborted
+type InFlight struct {
+	count atomic.Int64
+}
+
+// NewInFlight creates an empty in-flight tracker
+func NewInFlight() *InFlight {
+	return &InFlight{}
+}
+
+// Middleware tracks every request passing through it
+func (f *InFlight) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			f.count.Add(1)
+			defer f.count.Add(-1)
+			return next(c)
+		}
+	}
+}
+
+// Count returns the number of requests in flight right now
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight(t *testing.T) {
+	e := echo.New()
+	inFlight := NewInFlight()
+	e.Use(inFlight.Middleware())
+
+	var during int64
+	e.GET("/api/v1/health", func(c echo.Context) error {
+		during = inFlight.Count()
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+
+	assert.Equal(t, int64(1), during)
+	assert.Equal(t, int64(0), inFlight.Count())
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/labstack/gommon/random"
+	"stan.com/stantest/logging"
+)
+
+// inbound ids are only trusted when they are short and printable
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID makes sure every request carries an X-Request-ID.
+// An inbound id is honoured if it looks sane, otherwise a new one is generated.
+// The id is echoed in the response header and attached to a request scoped
+// logger so log lines of interleaved requests can be told apart.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := c.Response()
+
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !requestIDPattern.MatchString(id) {
+				id = random.String(32)
+				req.Header.Set(echo.HeaderXRequestID, id)
+			}
+			res.Header().Set(echo.HeaderXRequestID, id)
+			c.Set(logging.RequestIDKey, id)
+			c.SetLogger(logging.NewRequestLogger(c.Logger(), id, c.Path()))
+
+			start := time.Now()
+			err := next(c)
+
+			// an error is answered by the error handler once the chain returns
+			status := res.Status
+			if err != nil && !res.Committed {
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+			c.Logger().Infoj(log.JSON{
+				"message":     "request completed",
+				"method":      req.Method,
+				"status":      status,
+				"duration_ms": time.Since(start).Milliseconds(),
+			})
+			return err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		inboundID string
+		keepID    bool
+	}{
+		{
+			name:      "Inbound id is honoured",
+			inboundID: "abc-123",
+			keepID:    true,
+		},
+		{
+			name:      "Missing id is generated",
+			inboundID: "",
+			keepID:    false,
+		},
+		{
+			name:      "Unsafe inbound id is replaced",
+			inboundID: `bad"id${level}`,
+			keepID:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			e := echo.New()
+			e.Logger.SetOutput(&logs)
+			e.Logger.SetHeader(logging.Header())
+			e.Logger.SetLevel(log.DEBUG)
+			e.Use(RequestID())
+
+			var seenID string
+			e.GET("/api/v1/health", func(c echo.Context) error {
+				seenID = logging.RequestID(c)
+				c.Logger().Info("handling")
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+			if tt.inboundID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.inboundID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, seenID)
+			if tt.keepID {
+				assert.Equal(t, tt.inboundID, id)
+			} else {
+				assert.NotEqual(t, tt.inboundID, id)
+			}
+
+			// every log line must be valid JSON carrying the id and route
+			lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+			assert.Len(t, lines, 2)
+			for _, line := range lines {
+				var entry map[string]interface{}
+				assert.NoError(t, json.Unmarshal(line, &entry))
+				assert.Equal(t, id, entry["request_id"])
+				assert.Equal(t, "/api/v1/health", entry["route"])
+			}
+		})
+	}
+}
+
+func TestRequestIDLeavesErrorsToTheHandler(t *testing.T) {
+	var logs bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&logs)
+	e.Logger.SetHeader(logging.Header())
+	e.Logger.SetLevel(log.INFO)
+	handled := 0
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		handled++
+		e.DefaultHTTPErrorHandler(err, c)
+	}
+	e.Use(RequestID())
+	e.GET("/api/v1/health", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot, "short and stout")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	// answered once, by the router, not by the middleware as well
+	assert.Equal(t, 1, handled)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(logs.Bytes()), &entry))
+	assert.Equal(t, "request completed", entry["message"])
+	assert.Equal(t, float64(http.StatusTeapot), entry["status"])
+}
@@ -1,7 +1,7 @@
 package models
 
 type EpisodeRequest struct {
-	Payload []Episode `json:"payload"`
+	Payload []Episode `json:"payload" jsonschema:"required"`
 	Skip    int       `json:"skip"`
 	Take    int       `json:"take"`
 	Total   int       `json:"totalRecords"`
@@ -24,27 +24,107 @@ type Episode struct {
 }
 
 type Image struct {
-	ShowImage string `json:"showImage"`
+	ShowImage string           `json:"showImage"`
+	ImageSet  []ImageRendition `json:"imageSet"`
+}
+
+// image roles a rendition can play
+const (
+	ImageRolePoster    = "poster"
+	ImageRoleHero      = "hero"
+	ImageRoleThumbnail = "thumbnail"
+	ImageRoleLogo      = "logo"
+)
+
+// ImageRoles lists all known image roles
+var ImageRoles = []string{ImageRolePoster, ImageRoleHero, ImageRoleThumbnail, ImageRoleLogo}
+
+type ImageRendition struct {
+	Role   string `json:"role" jsonschema:"enumOf=imageRoles"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+	Format string `json:"format"`
 }
 
 type NextEpisode struct {
-	Channel     string `json:"channel"`
-	ChannelLogo string `json:"channelLogo"`
-	Date        string `json:"date"`
-	HTML        string `json:"html"`
-	URL         string `json:"url"`
+	Channel     string   `json:"channel"`
+	ChannelLogo string   `json:"channelLogo"`
+	Date        FeedTime `json:"date"`
+	HTML        string   `json:"html"`
+	URL         string   `json:"url"`
 }
 
 type Season struct {
-	Slug string `json:"slug"`
+	Slug         string `json:"slug"`
+	Number       int    `json:"number"`
+	Title        string `json:"title"`
+	EpisodeCount int    `json:"episodeCount"`
+	Year         int    `json:"year"`
+	DRM          bool   `json:"drm"`
+	// availability window, either end may be open
+	AvailableFrom FeedTime `json:"availableFrom"`
+	AvailableTo   FeedTime `json:"availableTo"`
 }
 
 type EpisodeResponse struct {
-	Response []EpisodeResponseItem `json:"response"`
+	Response []EpisodeResponseItem `json:"response" jsonschema:"required"`
+	Report   *ValidationReport     `json:"report,omitempty"`
 }
 
-type EpisodeResponseItem struct {
-	Image string `json:"image"`
+// SuggestResponse lists catalogue titles close to what was typed
+type SuggestResponse struct {
+	Query       string       `json:"query" jsonschema:"required"`
+	Suggestions []Suggestion `json:"suggestions" jsonschema:"required"`
+	// DidYouMean is the best suggestion when it isn't what was typed
+	DidYouMean string `json:"didYouMean,omitempty"`
+}
+
+// Suggestion is a show whose title or slug is close to the query
+type Suggestion struct {
+	Title string `json:"title" jsonschema:"required"`
+	Slug  string `json:"slug" jsonschema:"required"`
+	// Score is 1 for an exact match, falling with the typos needed
+	Score float64 `json:"score" jsonschema:"required"`
+	// Distance is the number of typos
+	Distance int `json:"distance" jsonschema:"required"`
+}
+
+// ValidationReport explains what happened to episodes which matched the
+// criteria, only sent when asked for with report=true
+type ValidationReport struct {
+	// Rejected episodes were left out of the response
+	Rejected []ValidationIssue `json:"rejected"`
+	// Modified episodes were changed before being returned
+	Modified []ValidationIssue `json:"modified"`
+	// Warnings flag values which were let through but look wrong
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+type ValidationIssue struct {
 	Slug  string `json:"slug"`
-	Title string `json:"title"`
+	Field string `json:"field,omitempty"`
+	// Rule is the id of the declared rule which raised the issue
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+type EpisodeResponseItem struct {
+	Image string `json:"image" jsonschema:"required"`
+	Slug  string `json:"slug" jsonschema:"required"`
+	Title string `json:"title" jsonschema:"required"`
+
+	// optional fields, only present when requested through the fields projection
+	NextEpisode *NextEpisode `json:"nextEpisode,omitempty"`
+	Seasons     []Season     `json:"seasons,omitempty"`
+	SeasonCount *int         `json:"seasonCount,omitempty"`
+	// PrimaryColor is canonical #rrggbb, TextColour contrasts with it
+	PrimaryColor string `json:"primaryColour,omitempty"`
+	TextColour   string `json:"textColour,omitempty"`
+	// canonical ISO 3166 country, BCP 47 language and genre codes
+	Country  string `json:"country,omitempty"`
+	Language string `json:"language,omitempty"`
+	Genre    string `json:"genre,omitempty"`
+	// Score is the relevance to the q and title searches, higher is better
+	Score *float64 `json:"score,omitempty"`
 }
@@ -0,0 +1,42 @@
+package models
+
+// FacetsResponse counts the episodes which matched a query by facet
+type FacetsResponse struct {
+	// Total is the number of episodes counted
+	Total int `json:"total" jsonschema:"required"`
+	// Facets maps facet names to their counts
+	Facets map[string]Facet `json:"facets" jsonschema:"required"`
+	// Pivot is only sent when asked for
+	Pivot        *FacetPivot `json:"pivot,omitempty"`
+	EpisodeCount Stats       `json:"episodeCount" jsonschema:"required"`
+}
+
+// Facet counts the episodes per value of one field
+type Facet struct {
+	Buckets []FacetBucket `json:"buckets" jsonschema:"required"`
+	// Missing counts the episodes without a value
+	Missing int `json:"missing" jsonschema:"required"`
+}
+
+type FacetBucket struct {
+	Value string `json:"value" jsonschema:"required"`
+	Count int    `json:"count" jsonschema:"required"`
+	// Pivot counts the second pivot facet within this bucket
+	Pivot []FacetBucket `json:"pivot,omitempty"`
+}
+
+// FacetPivot counts a second facet within each bucket of a first
+type FacetPivot struct {
+	Facets  []string      `json:"facets" jsonschema:"required"`
+	Buckets []FacetBucket `json:"buckets" jsonschema:"required"`
+}
+
+// Stats summarise a number over the episodes counted, minimum, maximum
+// and average are null when there are none
+type Stats struct {
+	Count int      `json:"count" jsonschema:"required"`
+	Sum   int      `json:"sum" jsonschema:"required"`
+	Min   *int     `json:"min"`
+	Max   *int     `json:"max"`
+	Avg   *float64 `json:"avg"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// feedTimeLayouts are the date formats seen in our upstream feeds
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// FeedTime is a date sent by an upstream feed. Raw keeps the original text
+// so an unparseable date can be reported instead of failing the whole request.
+// Decoding only keeps Raw, In reads it once the feed timezone is known.
+type FeedTime struct {
+	time.Time
+	Raw string
+	// sent tells an empty string apart from a missing date
+	sent bool
+}
+
+// ParseFeedTime parses s with the known feed layouts, dates without an
+// offset are taken to be in loc
+func ParseFeedTime(s string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Valid reports whether a date was sent and could be parsed
+func (f FeedTime) Valid() bool {
+	return !f.Time.IsZero()
+}
+
+// In reads Raw with dates without an offset taken to be in loc,
+// parse failures leave Time zero
+func (f FeedTime) In(loc *time.Location) FeedTime {
+	f.Time, _ = ParseFeedTime(f.Raw, loc)
+	return f
+}
+
+// UnmarshalJSON accepts a string or null and keeps the text for In
+func (f *FeedTime) UnmarshalJSON(data []byte) error {
+	var raw *string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = FeedTime{}
+	if raw == nil {
+		return nil
+	}
+	f.Raw = *raw
+	f.sent = true
+	return nil
+}
+
+// MarshalJSON writes RFC 3339, or the text as sent if it never parsed
+func (f FeedTime) MarshalJSON() ([]byte, error) {
+	if f.Valid() {
+		return json.Marshal(f.Time.Format(time.RFC3339))
+	}
+	if f.Raw == "" && !f.sent {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.Raw)
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedTimeUnmarshal(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+	tests := []struct {
+		name      string
+		json      string
+		wantValid bool
+		wantRaw   string
+		expected  time.Time
+	}{
+		{
+			name:      "RFC 3339 keeps its offset",
+			json:      `"2024-03-01T20:30:00Z"`,
+			wantValid: true,
+			wantRaw:   "2024-03-01T20:30:00Z",
+			expected:  time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "Local time is read in the feed timezone",
+			json:      `"2024-03-01 20:30:00"`,
+			wantValid: true,
+			wantRaw:   "2024-03-01 20:30:00",
+			expected:  time.Date(2024, 3, 1, 20, 30, 0, 0, sydney),
+		},
+		{
+			name:      "Australian day first date",
+			json:      `"02/03/2024"`,
+			wantValid: true,
+			wantRaw:   "02/03/2024",
+			expected:  time.Date(2024, 3, 2, 0, 0, 0, 0, sydney),
+		},
+		{
+			name:      "Null",
+			json:      `null`,
+			wantValid: false,
+		},
+		{
+			name:      "Unparseable date keeps the raw text",
+			json:      `"next tuesday"`,
+			wantValid: false,
+			wantRaw:   "next tuesday",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f FeedTime
+			assert.NoError(t, json.Unmarshal([]byte(tt.json), &f))
+			f = f.In(sydney)
+			assert.Equal(t, tt.wantValid, f.Valid())
+			assert.Equal(t, tt.wantRaw, f.Raw)
+			if tt.wantValid {
+				assert.True(t, tt.expected.Equal(f.Time), f.Time.String())
+			}
+		})
+	}
+}
+
+func TestFeedTimeMarshal(t *testing.T) {
+	valid := FeedTime{Time: time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC), Raw: "2024-03-01 20:30:00"}
+	b, err := json.Marshal(valid)
+	assert.NoError(t, err)
+	assert.Equal(t, `"2024-03-01T20:30:00Z"`, string(b))
+
+	b, err = json.Marshal(FeedTime{})
+	assert.NoError(t, err)
+	assert.Equal(t, `null`, string(b))
+
+	// what was sent comes back, an empty date stays empty
+	for _, sent := range []string{`""`, `null`, `"next tuesday"`} {
+		var f FeedTime
+		assert.NoError(t, json.Unmarshal([]byte(sent), &f))
+		b, err = json.Marshal(f.In(time.UTC))
+		assert.NoError(t, err)
+		assert.Equal(t, sent, string(b))
+	}
+}
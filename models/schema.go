@@ -0,0 +1,51 @@
+package models
+
+import (
+	_ "embed"
+	"reflect"
+
+	"stan.com/stantest/schema"
+)
+
+// EpisodeSchemaID identifies the published schema
+const EpisodeSchemaID = "/api/v1/schema"
+
+// ShowsSchemaID identifies the published v2 schema
+const ShowsSchemaID = "/api/v2/schema"
+
+// EpisodeSchema is the published JSON Schema of the episode API. Its root
+// is EpisodeRequest, the other types are under $defs. Regenerate it with
+// go test ./models -run TestEpisodeSchema -update
+//
+//go:embed episode.schema.json
+var EpisodeSchema []byte
+
+// ShowsSchema is the published JSON Schema of the v2 API. Its root is
+// ShowsRequest, the response is under $defs. Regenerate it with
+// go test ./models -run TestShowsSchema -update
+//
+//go:embed shows.schema.json
+var ShowsSchema []byte
+
+// GenerateEpisodeSchema reflects the schema from the request and response types
+func GenerateEpisodeSchema() *schema.Schema {
+	return newSchemaGenerator().Bundle(EpisodeSchemaID, "Stan episode API", EpisodeRequest{}, EpisodeResponse{},
+		ShowsRequest{}, ShowsResponse{}, SuggestResponse{}, FacetsResponse{})
+}
+
+// GenerateShowsSchema reflects the v2 schema from its request and response types
+func GenerateShowsSchema() *schema.Schema {
+	return newSchemaGenerator().Bundle(ShowsSchemaID, "Stan shows API", ShowsRequest{}, ShowsResponse{})
+}
+
+// newSchemaGenerator knows the types with their own JSON encoding and
+// the value lists kept in Go
+func newSchemaGenerator() *schema.Generator {
+	generator := schema.NewGenerator()
+	generator.Overrides[reflect.TypeOf(FeedTime{})] = &schema.Schema{
+		Type:        schema.Types{"string", "null"},
+		Description: "RFC 3339, or a feed date in the configured feed timezone",
+	}
+	generator.Enums["imageRoles"] = ImageRoles
+	return generator
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/schema"
+)
+
+var update = flag.Bool("update", false, "rewrite the published schemas from the Go types")
+
+// TestEpisodeSchema fails when the structs and the published schema drift apart
+func TestEpisodeSchema(t *testing.T) {
+	generated, err := json.MarshalIndent(GenerateEpisodeSchema(), "", "  ")
+	assert.NoError(t, err)
+	generated = append(generated, '\n')
+
+	if *update {
+		assert.NoError(t, os.WriteFile("episode.schema.json", generated, 0o644))
+		return
+	}
+	assert.JSONEq(t, string(generated), string(EpisodeSchema),
+		"episode.schema.json is out of date, run go test ./models -run TestEpisodeSchema -update")
+}
+
+// TestShowsSchema fails when the v2 structs and their published schema drift apart
+func TestShowsSchema(t *testing.T) {
+	generated, err := json.MarshalIndent(GenerateShowsSchema(), "", "  ")
+	assert.NoError(t, err)
+	generated = append(generated, '\n')
+
+	if *update {
+		assert.NoError(t, os.WriteFile("shows.schema.json", generated, 0o644))
+		return
+	}
+	assert.JSONEq(t, string(generated), string(ShowsSchema),
+		"shows.schema.json is out of date, run go test ./models -run TestShowsSchema -update")
+}
+
+func TestEpisodeSchemaValidatesSampleRequest(t *testing.T) {
+	published, err := schema.Parse(EpisodeSchema)
+	assert.NoError(t, err)
+
+	violations, err := published.ValidateJSON([]byte(`{"payload": [{
+		"country": "UK", "description": "", "drm": true, "episodeCount": 3, "genre": "Reality",
+		"image": {"showImage": "http://catchup.ninemsn.com.au/img/jump-in/shows/16KidsandCounting1280.jpg"},
+		"language": "English", "nextEpisode": null, "primaryColour": "#ff7800",
+		"seasons": [{"slug": "show/16kidsandcounting/season/1"}],
+		"slug": "show/16kidsandcounting", "title": "16 Kids and Counting", "tvChannel": "GEM"
+	}], "skip": 0, "take": 10, "totalRecords": 75}`))
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestShowsSchemaValidatesSampleRequest(t *testing.T) {
+	published, err := schema.Parse(ShowsSchema)
+	assert.NoError(t, err)
+
+	violations, err := published.ValidateJSON([]byte(`{"data": [{
+		"drm": true, "episodeCount": 3, "slug": "show/16kidsandcounting", "title": "16 Kids and Counting",
+		"image": {"showImage": "http://catchup.ninemsn.com.au/img/jump-in/shows/16KidsandCounting1280.jpg"},
+		"primaryColor": "#ff7800", "seasons": [{"slug": "show/16kidsandcounting/season/1"}]
+	}]}`))
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	// a v1 body is not a v2 request
+	violations, err = published.ValidateJSON([]byte(`{"payload": []}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.Violation{{Pointer: "/data", Message: "is required"}}, violations)
+}
@@ -0,0 +1,124 @@
+package models
+
+// The v2 contract wraps every response in a data, meta and errors envelope
+// and spells every field in camelCase. Shows convert to and from the v1
+// types, which the processing core works on.
+
+// ShowsRequest is the v2 request body
+type ShowsRequest struct {
+	Data []Show `json:"data" jsonschema:"required"`
+}
+
+// Show is a show in the v2 contract, it has the fields of Episode
+type Show struct {
+	Country      string       `json:"country"`
+	Description  string       `json:"description"`
+	DRM          bool         `json:"drm"`
+	EpisodeCount int          `json:"episodeCount"`
+	Genre        string       `json:"genre"`
+	Image        Image        `json:"image"`
+	Language     string       `json:"language"`
+	NextEpisode  *NextEpisode `json:"nextEpisode"`
+	PrimaryColor string       `json:"primaryColor"`
+	Seasons      []Season     `json:"seasons"`
+	Slug         string       `json:"slug"`
+	Title        string       `json:"title"`
+	TVChannel    string       `json:"tvChannel"`
+}
+
+// Episode converts the show to the v1 type
+func (s Show) Episode() Episode {
+	return Episode(s)
+}
+
+// ShowsResponse is the v2 response body, Data is null when the request failed
+type ShowsResponse struct {
+	Data   []ShowItem `json:"data"`
+	Meta   Meta       `json:"meta" jsonschema:"required"`
+	Errors []APIError `json:"errors" jsonschema:"required"`
+}
+
+// ShowItem is a matched show, optional fields are only set when requested
+type ShowItem struct {
+	Image        string       `json:"image" jsonschema:"required"`
+	Slug         string       `json:"slug" jsonschema:"required"`
+	Title        string       `json:"title" jsonschema:"required"`
+	NextEpisode  *NextEpisode `json:"nextEpisode,omitempty"`
+	Seasons      []Season     `json:"seasons,omitempty"`
+	SeasonCount  *int         `json:"seasonCount,omitempty"`
+	PrimaryColor string       `json:"primaryColor,omitempty"`
+	TextColor    string       `json:"textColor,omitempty"`
+	Country      string       `json:"country,omitempty"`
+	Language     string       `json:"language,omitempty"`
+	Genre        string       `json:"genre,omitempty"`
+	Score        *float64     `json:"score,omitempty"`
+}
+
+// NewShowItem converts a v1 response item
+func NewShowItem(item EpisodeResponseItem) ShowItem {
+	return ShowItem{
+		Image:        item.Image,
+		Slug:         item.Slug,
+		Title:        item.Title,
+		NextEpisode:  item.NextEpisode,
+		Seasons:      item.Seasons,
+		SeasonCount:  item.SeasonCount,
+		PrimaryColor: item.PrimaryColor,
+		TextColor:    item.TextColour,
+		Country:      item.Country,
+		Language:     item.Language,
+		Genre:        item.Genre,
+		Score:        item.Score,
+	}
+}
+
+// Meta describes the page of shows returned
+type Meta struct {
+	// RequestID is the id the request is logged with
+	RequestID string `json:"requestId,omitempty"`
+	Page      Page   `json:"page" jsonschema:"required"`
+	// Report lists modified shows and warnings, only sent with report=true
+	Report *MetaReport `json:"report,omitempty"`
+}
+
+// Page is the pagination of the matched shows
+type Page struct {
+	// Total is the number of shows which matched before paging
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	// Limit is the page size asked for, 0 means all
+	Limit int `json:"limit"`
+	// Count is the number of shows in data
+	Count int `json:"count"`
+}
+
+// MetaReport is the part of the validation report which isn't an error
+type MetaReport struct {
+	Modified []ValidationIssue `json:"modified"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// APIError is a problem with the request or one of its shows
+type APIError struct {
+	// Code is a stable machine readable error code
+	Code    string `json:"code" jsonschema:"required"`
+	Message string `json:"message" jsonschema:"required"`
+	// Slug and Field locate problems with a single show
+	Slug  string `json:"slug,omitempty"`
+	Field string `json:"field,omitempty"`
+	// Rule is the declared rule which was broken
+	Rule string `json:"rule,omitempty"`
+	// Pointer is the RFC 6901 JSON pointer of a schema violation
+	Pointer string `json:"pointer,omitempty"`
+}
+
+// v2 error codes
+const (
+	ErrorCodeInvalidBody         = "invalid_body"
+	ErrorCodeBodyTooLarge        = "body_too_large"
+	ErrorCodeUnsupportedEncoding = "unsupported_encoding"
+	ErrorCodeInvalidQuery        = "invalid_query"
+	ErrorCodeSchema              = "schema_violation"
+	ErrorCodeRejected            = "show_rejected"
+	ErrorCodeDeadline            = "deadline_exceeded"
+)
@@ -0,0 +1,456 @@
+package openapi
+
+import (
+	_ "embed"
+
+	"stan.com/stantest/models"
+	"stan.com/stantest/schema"
+)
+
+// Version is the OpenAPI version of the document
+const Version = "3.1.0"
+
+// DocsPage renders /openapi.json with Redoc
+//
+//go:embed docs.html
+var DocsPage []byte
+
+// RedocScript is the Redoc 2.0.0-rc.59 standalone bundle DocsPage loads,
+// served by the API so the docs work without reaching a CDN. Its license
+// is in redoc.LICENSE.
+//
+//go:embed redoc.standalone.js
+var RedocScript []byte
+
+// Document is the subset of OpenAPI 3.1 the server describes itself with
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *schema.Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *schema.Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*schema.Schema `json:"schemas"`
+}
+
+// HasOperation reports whether the document describes method on path,
+// path uses OpenAPI {param} templates
+func (d *Document) HasOperation(method, path string) bool {
+	item, ok := d.Paths[path]
+	if !ok {
+		return false
+	}
+	_, ok = item[method]
+	return ok
+}
+
+// componentRef points at a schema under components
+func componentRef(name string) *schema.Schema {
+	return &schema.Schema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonContent(s *schema.Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+func stringParam(name, description string, enum ...string) Parameter {
+	s := &schema.Schema{Type: schema.Types{"string"}}
+	for _, value := range enum {
+		s.Enum = append(s.Enum, value)
+	}
+	return Parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+func typedParam(name, kind, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &schema.Schema{Type: schema.Types{kind}}}
+}
+
+// errorSchemas describe the error bodies every endpoint can send
+func errorSchemas() map[string]*schema.Schema {
+	str := func() *schema.Schema { return &schema.Schema{Type: schema.Types{"string"}} }
+	return map[string]*schema.Schema{
+		"Error": {
+			Type: schema.Types{"object"},
+			Properties: map[string]*schema.Schema{
+				"error":      str(),
+				"request_id": str(),
+			},
+			Required: []string{"error"},
+		},
+		"SchemaError": {
+			Type: schema.Types{"object"},
+			Properties: map[string]*schema.Schema{
+				"error":      str(),
+				"request_id": str(),
+				"violations": {
+					Type: schema.Types{"array"},
+					Items: &schema.Schema{
+						Type: schema.Types{"object"},
+						Properties: map[string]*schema.Schema{
+							"pointer": {Type: schema.Types{"string"}, Description: "RFC 6901 JSON pointer into the request body"},
+							"message": str(),
+						},
+						Required: []string{"pointer", "message"},
+					},
+				},
+			},
+			Required: []string{"error", "violations"},
+		},
+		"Health": {
+			Type:       schema.Types{"object"},
+			Properties: map[string]*schema.Schema{"status": {Type: schema.Types{"string"}, Enum: []any{"ok"}}},
+			Required:   []string{"status"},
+		},
+	}
+}
+
+// Build describes every route the API serves
+func Build() *Document {
+	bundle := models.GenerateEpisodeSchema()
+	bundle.RewriteRefs("#/$defs/", "#/components/schemas/")
+	schemas := bundle.Defs
+	for name, s := range errorSchemas() {
+		schemas[name] = s
+	}
+
+	badRequest := Response{Description: "The body or a query parameter is invalid", Content: jsonContent(&schema.Schema{
+		AnyOf: []*schema.Schema{componentRef("Error"), componentRef("SchemaError")},
+	})}
+	notModified := Response{Description: "The If-None-Match ETag is still current, responses carry an ETag of their content"}
+	unavailable := Response{Description: "The processing deadline passed", Content: jsonContent(componentRef("Error"))}
+	tooLarge := Response{Description: "A compressed body inflates past its limit, or a body is over the size limit when one is configured", Content: jsonContent(componentRef("Error"))}
+	unsupportedEncoding := Response{Description: "The body's Content-Encoding is not gzip, zstd or identity", Content: jsonContent(componentRef("Error"))}
+
+	return &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "Stan episode server",
+			Version:     "1",
+			Description: "Filters a feed of shows down to the DRM protected ones with episodes.",
+		},
+		Paths: map[string]PathItem{
+			"/api/v1/episodes": {
+				"post": {
+					OperationID: "filterEpisodes",
+					Summary:     "Filter a feed of shows",
+					Description: "Returns the shows with DRM enabled and at least one episode, cleaned up and validated. " +
+						"Invalid shows are left out, ask for the report to see why.",
+					Tags:       []string{"episodes"},
+					Parameters: episodeParameters(),
+					RequestBody: &RequestBody{
+						Description: "The feed to filter, it may be sent with Content-Encoding gzip or zstd",
+						Required:    true,
+						Content:     jsonContent(componentRef("EpisodeRequest")),
+					},
+					Responses: map[string]Response{
+						"200": {Description: "The matching shows", Content: jsonContent(componentRef("EpisodeResponse"))},
+						"304": notModified,
+						"400": badRequest,
+						"413": tooLarge,
+						"415": unsupportedEncoding,
+						"503": unavailable,
+					},
+				},
+			},
+			"/api/v1/shows": {
+				"get": {
+					OperationID: "listShows",
+					Summary:     "Query the uploaded catalogue",
+					Description: "Runs filterEpisodes over the catalogue uploaded through the admin server. " +
+						"Unknown or repeated query parameters are refused. Responses carry an ETag of their content " +
+						"and answer If-None-Match with 304.",
+					Tags:       []string{"shows"},
+					Parameters: append(showsParameters(), episodeParameters()...),
+					Responses: map[string]Response{
+						"200": {Description: "The matching shows", Content: jsonContent(componentRef("EpisodeResponse"))},
+						"304": {Description: "The cached response is still current"},
+						"400": {Description: "A query parameter is invalid, unknown or repeated", Content: jsonContent(componentRef("Error"))},
+						"404": {Description: "No catalogue has been uploaded", Content: jsonContent(componentRef("Error"))},
+						"503": unavailable,
+					},
+				},
+			},
+			"/api/v1/episodes/facets": {
+				"post": {
+					OperationID: "facetEpisodes",
+					Summary:     "Count a feed of shows by facet",
+					Description: "Counts the shows matching the filters by genre, country, language, tvChannel, drm and " +
+						"episodeCount bucket, with episodeCount statistics. Unlike filterEpisodes drm and minEpisodes " +
+						"don't default. Shows are counted as filterEpisodes would return them, duplicates once and " +
+						"invalid shows not at all.",
+					Tags:       []string{"facets"},
+					Parameters: facetParameters(),
+					RequestBody: &RequestBody{
+						Description: "The feed to count, it may be sent with Content-Encoding gzip or zstd",
+						Required:    true,
+						Content:     jsonContent(componentRef("EpisodeRequest")),
+					},
+					Responses: map[string]Response{
+						"200": {Description: "The counts", Content: jsonContent(componentRef("FacetsResponse"))},
+						"304": notModified,
+						"400": badRequest,
+						"413": tooLarge,
+						"415": unsupportedEncoding,
+						"503": unavailable,
+					},
+				},
+			},
+			"/api/v1/shows/facets": {
+				"get": {
+					OperationID: "facetShows",
+					Summary:     "Count the uploaded catalogue by facet",
+					Description: "facetEpisodes over the catalogue uploaded through the admin server. Unknown or repeated " +
+						"query parameters are refused.",
+					Tags:       []string{"facets"},
+					Parameters: facetParameters(),
+					Responses: map[string]Response{
+						"200": {Description: "The counts", Content: jsonContent(componentRef("FacetsResponse"))},
+						"304": {Description: "The cached response is still current"},
+						"400": {Description: "A query parameter is invalid, unknown or repeated", Content: jsonContent(componentRef("Error"))},
+						"404": {Description: "No catalogue has been uploaded", Content: jsonContent(componentRef("Error"))},
+						"503": unavailable,
+					},
+				},
+			},
+			"/api/v1/shows/suggest": {
+				"get": {
+					OperationID: "suggestShows",
+					Summary:     "Suggest titles from the uploaded catalogue",
+					Description: "Autocomplete over the titles and slugs of the shows listShows returns by default. " +
+						"Typos are tolerated and the last word may be unfinished. didYouMean is set when the " +
+						"best suggestion isn't what was typed.",
+					Tags: []string{"shows"},
+					Parameters: []Parameter{
+						{Name: "q", In: "query", Required: true, Description: "What was typed so far", Schema: &schema.Schema{Type: schema.Types{"string"}}},
+						typedParam("limit", "integer", "Maximum number of suggestions"),
+					},
+					Responses: map[string]Response{
+						"200": {Description: "The closest titles, best first", Content: jsonContent(componentRef("SuggestResponse"))},
+						"304": {Description: "The cached response is still current"},
+						"400": {Description: "A query parameter is invalid, unknown or repeated", Content: jsonContent(componentRef("Error"))},
+						"404": {Description: "No catalogue has been uploaded", Content: jsonContent(componentRef("Error"))},
+					},
+				},
+			},
+			"/api/v1/schema": {
+				"get": {
+					OperationID: "getSchema",
+					Summary:     "JSON Schema of the request and response bodies",
+					Tags:        []string{"docs"},
+					Responses: map[string]Response{
+						"200": {Description: "A JSON Schema 2020-12 bundle rooted at EpisodeRequest", Content: map[string]MediaType{
+							"application/schema+json": {Schema: &schema.Schema{Type: schema.Types{"object"}}},
+						}},
+					},
+				},
+			},
+			"/api/v1/health": {
+				"get": {
+					OperationID: "health",
+					Summary:     "Liveness check",
+					Tags:        []string{"ops"},
+					Responses: map[string]Response{
+						"200": {Description: "The server is up", Content: jsonContent(componentRef("Health"))},
+					},
+				},
+			},
+			"/api/v2/shows": {
+				"post": {
+					OperationID: "filterShows",
+					Summary:     "Filter a feed of shows",
+					Description: "The v2 contract of filterEpisodes. Matched shows are paged through offset and limit, " +
+						"shows which were rejected are listed under errors.",
+					Tags:       []string{"shows"},
+					Parameters: append(showParameters(), pageParameters()...),
+					RequestBody: &RequestBody{
+						Description: "The feed to filter, it may be sent with Content-Encoding gzip or zstd",
+						Required:    true,
+						Content:     jsonContent(componentRef("ShowsRequest")),
+					},
+					Responses: map[string]Response{
+						"200": {Description: "A page of the matching shows", Content: jsonContent(componentRef("ShowsResponse"))},
+						"304": notModified,
+						"400": {Description: "The body or a query parameter is invalid, data is null", Content: jsonContent(componentRef("ShowsResponse"))},
+						"413": {Description: "A compressed body inflates past its limit, or a body is over the size limit when one is configured", Content: jsonContent(componentRef("ShowsResponse"))},
+						"415": {Description: "The body's Content-Encoding is not gzip, zstd or identity", Content: jsonContent(componentRef("ShowsResponse"))},
+						"503": {Description: "The processing deadline passed, data is null", Content: jsonContent(componentRef("ShowsResponse"))},
+					},
+				},
+			},
+			"/api/v2/schema": {
+				"get": {
+					OperationID: "getSchemaV2",
+					Summary:     "JSON Schema of the request and response bodies",
+					Tags:        []string{"docs"},
+					Responses: map[string]Response{
+						"200": {Description: "A JSON Schema 2020-12 bundle rooted at ShowsRequest, ShowsResponse is among its definitions", Content: map[string]MediaType{
+							"application/schema+json": {Schema: &schema.Schema{Type: schema.Types{"object"}}},
+						}},
+					},
+				},
+			},
+			"/api/v2/health": {
+				"get": {
+					OperationID: "healthV2",
+					Summary:     "Liveness check",
+					Tags:        []string{"ops"},
+					Responses: map[string]Response{
+						"200": {Description: "The server is up", Content: jsonContent(componentRef("Health"))},
+					},
+				},
+			},
+			"/openapi.json": {
+				"get": {
+					OperationID: "getOpenAPI",
+					Summary:     "This document",
+					Tags:        []string{"docs"},
+					Responses: map[string]Response{
+						"200": {Description: "The OpenAPI document", Content: jsonContent(&schema.Schema{Type: schema.Types{"object"}})},
+					},
+				},
+			},
+			"/docs/redoc.standalone.js": {
+				"get": {
+					OperationID: "getDocsScript",
+					Summary:     "The Redoc bundle the documentation page runs",
+					Tags:        []string{"docs"},
+					Responses: map[string]Response{
+						"200": {Description: "The script", Content: map[string]MediaType{
+							"text/javascript": {Schema: &schema.Schema{Type: schema.Types{"string"}}},
+						}},
+						"304": notModified,
+					},
+				},
+			},
+			"/docs": {
+				"get": {
+					OperationID: "getDocs",
+					Summary:     "Interactive API documentation",
+					Tags:        []string{"docs"},
+					Responses: map[string]Response{
+						"200": {Description: "An HTML page rendering this document", Content: map[string]MediaType{
+							"text/html": {Schema: &schema.Schema{Type: schema.Types{"string"}}},
+						}},
+					},
+				},
+			},
+		},
+		Components: Components{Schemas: schemas},
+	}
+}
+
+// episodeParameters are the query parameters of POST /api/v1/episodes
+func episodeParameters() []Parameter {
+	return []Parameter{
+		stringParam("imageRole", "Pick the image rendition with this role for the image field", models.ImageRoles...),
+		typedParam("maxWidth", "integer", "Pick the widest rendition at most this wide"),
+		typedParam("airsWithinDays", "integer", "Only shows whose next episode airs within this many days"),
+		typedParam("hasUpcoming", "boolean", "Only shows with (true) or without (false) a next episode"),
+		typedParam("minSeasons", "integer", "Only shows with at least this many seasons"),
+		typedParam("latestSeasonDrm", "boolean", "Only shows whose latest season has DRM set as given"),
+		stringParam("country", "Only shows from this ISO 3166 country"),
+		stringParam("language", "Only shows in this BCP 47 language"),
+		stringParam("genre", "Only shows in this genre, codes, names and aliases are accepted"),
+		stringParam("q", "Only shows whose title or description has every word and \"quoted phrase\", "+
+			"most relevant first. Case, accents and English word endings are ignored"),
+		stringParam("title", "Only shows whose title or slug is this allowing for typos, closest first"),
+		typedParam("report", "boolean", "Add the validation report to the response"),
+		stringParam("fields", "Comma separated optional response fields: nextEpisode, seasons, seasonCount, "+
+			"primaryColour, textColour, country, language, genre"),
+		stringParam("profile", "Declared validation rule profile to apply"),
+	}
+}
+
+// showParameters are the episodeParameters of POST /api/v2/shows, which
+// spells the colour fields in camelCase
+func showParameters() []Parameter {
+	parameters := episodeParameters()
+	for i, parameter := range parameters {
+		if parameter.Name == "fields" {
+			parameters[i] = stringParam("fields", "Comma separated optional response fields: nextEpisode, seasons, "+
+				"seasonCount, primaryColor, textColor, country, language, genre")
+		}
+	}
+	return parameters
+}
+
+// showsParameters select from the stored catalogue in GET /api/v1/shows
+func showsParameters() []Parameter {
+	return []Parameter{
+		typedParam("drm", "boolean", "Only shows with DRM set as given, defaults to true"),
+		typedParam("minEpisodes", "integer", "Only shows with at least this many episodes, defaults to 1"),
+	}
+}
+
+// facetParameters filter the shows counted and pick what to count
+func facetParameters() []Parameter {
+	parameters := []Parameter{
+		typedParam("drm", "boolean", "Only shows with DRM set as given"),
+		typedParam("minEpisodes", "integer", "Only shows with at least this many episodes"),
+	}
+	for _, parameter := range episodeParameters() {
+		switch parameter.Name {
+		case "imageRole", "maxWidth", "report", "fields", "profile":
+		default:
+			parameters = append(parameters, parameter)
+		}
+	}
+	return append(parameters,
+		stringParam("facets", "Comma separated facets to count: genre, country, language, tvChannel, drm, "+
+			"episodeCount. All of them when absent"),
+		stringParam("pivot", "Two comma separated facets, counts the second within each bucket of the first"),
+		stringParam("episodeBuckets", "Comma separated ascending lower bounds of the episodeCount buckets, "+
+			"1,6,11,26,51 when absent"),
+	)
+}
+
+// pageParameters page through the matched shows in v2
+func pageParameters() []Parameter {
+	return []Parameter{
+		typedParam("offset", "integer", "Number of matched shows to skip"),
+		typedParam("limit", "integer", "Maximum number of shows to return, 0 or absent returns all"),
+	}
+}
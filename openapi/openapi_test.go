@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRefsResolve checks every $ref in the document points at a component
+func TestRefsResolve(t *testing.T) {
+	document := Build()
+	raw, err := json.Marshal(document)
+	assert.NoError(t, err)
+
+	refs := regexp.MustCompile(`"\$ref":"([^"]+)"`).FindAllStringSubmatch(string(raw), -1)
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		name, found := strings.CutPrefix(ref[1], "#/components/schemas/")
+		assert.True(t, found, ref[1])
+		assert.Contains(t, document.Components.Schemas, name, "unresolved %s", ref[1])
+	}
+	assert.NotContains(t, string(raw), "$defs")
+}
+
+func TestBuild(t *testing.T) {
+	document := Build()
+
+	assert.Equal(t, "3.1.0", document.OpenAPI)
+	assert.True(t, document.HasOperation("post", "/api/v1/episodes"))
+	assert.False(t, document.HasOperation("get", "/api/v1/episodes"))
+
+	operation := document.Paths["/api/v1/episodes"]["post"]
+	assert.Equal(t, "#/components/schemas/EpisodeRequest", operation.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, operation.Responses, "400")
+	for _, name := range []string{"EpisodeRequest", "EpisodeResponse", "Episode", "Error", "SchemaError"} {
+		assert.Contains(t, document.Components.Schemas, name)
+	}
+}
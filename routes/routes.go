@@ -2,24 +2,51 @@ package routes
 
 import (
 	"github.com/labstack/echo/v4"
+	"stan.com/stantest/config"
 	"stan.com/stantest/controllers"
+	"stan.com/stantest/middlewares"
 )
 
-func SetupRoutes(e *echo.Echo) {
-	// episode processing api version 1
-	v1 := e.Group("/api/v1")
+func SetupRoutes(e *echo.Echo, cfg *config.Config) {
+	// machine readable API description and its rendering,
+	// every route below must be documented in package openapi
+	e.GET("/openapi.json", controllers.GetOpenAPI)
+	e.GET("/docs", controllers.GetDocs)
+	e.GET("/docs/redoc.standalone.js", controllers.GetDocsScript)
+
+	// checking healthy maybe needed by third party
+	health := func(c echo.Context) error {
+		return c.JSON(200, map[string]string{
+			"status": "ok",
+		})
+	}
+
+	// episode processing api version 1, kept stable for existing clients
+	v1 := e.Group("/api/v1", middlewares.Deprecation(cfg.API.V1Deprecation, cfg.API.V1Sunset, cfg.API.V1DeprecationLink))
 	{
 		// controllers mapping
 		users := v1.Group("/episodes")
 		{
 			users.POST("", controllers.DealwithEpisodes)
+			users.POST("/facets", controllers.FacetEpisodes)
 		}
 
-		// checking healthy maybe needed by third party
-		v1.GET("/health", func(c echo.Context) error {
-			return c.JSON(200, map[string]string{
-				"status": "ok",
-			})
-		})
+		// queries over the stored catalogue
+		v1.GET("/shows", controllers.ListShows)
+		v1.GET("/shows/suggest", controllers.SuggestShows)
+		v1.GET("/shows/facets", controllers.FacetShows)
+
+		// JSON Schema of the request and response bodies
+		v1.GET("/schema", controllers.GetSchema)
+
+		v1.GET("/health", health)
+	}
+
+	// version 2 answers with a data, meta and errors envelope
+	v2 := e.Group("/api/v2")
+	{
+		v2.POST("/shows", controllers.FilterShows)
+		v2.GET("/schema", controllers.GetShowsSchema)
+		v2.GET("/health", health)
 	}
 }
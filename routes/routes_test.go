@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+	"stan.com/stantest/openapi"
+)
+
+// echoParam matches :name path parameters
+var echoParam = regexp.MustCompile(`:([^/]+)`)
+
+// TestEveryRouteIsDocumented walks the echo route table
+func TestEveryRouteIsDocumented(t *testing.T) {
+	e := echo.New()
+	SetupRoutes(e, config.Default())
+	document := openapi.Build()
+
+	routes := e.Routes()
+	assert.NotEmpty(t, routes)
+	documented := 0
+	for _, route := range routes {
+		// echo adds catch all routes for unmatched methods on groups
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		path := echoParam.ReplaceAllString(route.Path, "{$1}")
+		assert.True(t, document.HasOperation(strings.ToLower(route.Method), path),
+			"%s %s is not in the OpenAPI document", route.Method, route.Path)
+		documented++
+	}
+
+	// and nothing is documented which isn't served
+	operations := 0
+	for _, item := range document.Paths {
+		operations += len(item)
+	}
+	assert.Equal(t, documented, operations)
+}
+
+func TestDocumentEndpoints(t *testing.T) {
+	e := echo.New()
+	SetupRoutes(e, config.Default())
+
+	for _, path := range []string{"/openapi.json", "/docs", "/docs/redoc.standalone.js", "/api/v1/schema", "/api/v1/health", "/api/v2/schema"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestSchemaPerVersion(t *testing.T) {
+	e := echo.New()
+	SetupRoutes(e, config.Default())
+
+	tests := []struct {
+		path       string
+		root       string
+		properties []string
+	}{
+		{path: "/api/v1/schema", root: "EpisodeRequest", properties: []string{"payload", "skip", "take", "totalRecords"}},
+		{path: "/api/v2/schema", root: "ShowsRequest", properties: []string{"data"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var document struct {
+				ID   string `json:"$id"`
+				Ref  string `json:"$ref"`
+				Defs map[string]struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"$defs"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &document))
+			assert.Equal(t, tt.path, document.ID)
+			assert.Equal(t, "#/$defs/"+tt.root, document.Ref)
+			var properties []string
+			for name := range document.Defs[tt.root].Properties {
+				properties = append(properties, name)
+			}
+			assert.ElementsMatch(t, tt.properties, properties)
+		})
+	}
+}
+
+func TestV1Deprecation(t *testing.T) {
+	cfg := config.Default()
+	cfg.API.V1Deprecation = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg.API.V1Sunset = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	SetupRoutes(e, cfg)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	assert.Equal(t, "@1735689600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Jan 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/health", nil))
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+}
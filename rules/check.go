@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"stan.com/stantest/urlpolicy"
+)
+
+// links only need to be well formed, the URL policy has the final say
+var anyURL = &urlpolicy.Policy{}
+
+// check returns the violation message and whether the rule held,
+// get reads a field of the value being checked
+func (r *rule) check(get func(path string) (any, bool)) (string, bool) {
+	value, present := get(r.Field)
+
+	if r.Type == TypeRequired {
+		return r.Field + " is required", present
+	}
+	if !present {
+		return "", true
+	}
+
+	switch r.Type {
+	case TypeRegex:
+		s, ok := value.(string)
+		return fmt.Sprintf("%s must match %s", r.Field, r.Pattern), ok && r.pattern.MatchString(s)
+	case TypeEnum:
+		return fmt.Sprintf("%s must be one of %s", r.Field, strings.Join(r.Values, ", ")), slices.Contains(r.Values, scalar(value))
+	case TypeURL:
+		s, ok := value.(string)
+		return r.Field + " must be a valid URL", ok && anyURL.Check(s).OK()
+	case TypeLength:
+		n, ok := length(value)
+		return r.Field + " length " + bounds(r.Min, r.Max), ok && within(float64(n), r.Min, r.Max)
+	case TypeRange:
+		n, ok := value.(float64)
+		return r.Field + " " + bounds(r.Min, r.Max), ok && within(n, r.Min, r.Max)
+	case TypeCrossField:
+		other, otherPresent := get(r.Other)
+		if r.Op == "requires" {
+			return r.Field + " requires " + r.Other, otherPresent
+		}
+		message := fmt.Sprintf("%s must be %s %s", r.Field, operators[r.Op], r.Other)
+		if !otherPresent {
+			return "", true
+		}
+		cmp, ok := compare(value, other)
+		if !ok {
+			return message, false
+		}
+		switch r.Op {
+		case "eq":
+			return message, cmp == 0
+		case "ne":
+			return message, cmp != 0
+		case "lt":
+			return message, cmp < 0
+		case "lte":
+			return message, cmp <= 0
+		case "gt":
+			return message, cmp > 0
+		default:
+			return message, cmp >= 0
+		}
+	}
+	return "", true
+}
+
+// present reports whether a value read from a field counts as given,
+// empty strings, nulls and empty lists count as missing
+func present(value any) (any, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return v, v != ""
+	case []any:
+		return v, len(v) > 0
+	case map[string]any:
+		return v, len(v) > 0
+	}
+	return value, true
+}
+
+// scalar formats a value for enum matching
+func scalar(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// length counts the characters of a string or the items of a list
+func length(value any) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), true
+	case []any:
+		return len(v), true
+	}
+	return 0, false
+}
+
+func within(n float64, min, max *float64) bool {
+	return (min == nil || n >= *min) && (max == nil || n <= *max)
+}
+
+func bounds(min, max *float64) string {
+	format := func(f *float64) string { return strconv.FormatFloat(*f, 'f', -1, 64) }
+	switch {
+	case min != nil && max != nil:
+		return "must be between " + format(min) + " and " + format(max)
+	case min != nil:
+		return "must be at least " + format(min)
+	default:
+		return "must be at most " + format(max)
+	}
+}
+
+// compare orders two numbers, two RFC 3339 dates or two strings
+func compare(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			break
+		}
+		tx, errX := time.Parse(time.RFC3339, x)
+		ty, errY := time.Parse(time.RFC3339, y)
+		if errX == nil && errY == nil {
+			return tx.Compare(ty), true
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		if y, ok := b.(bool); ok && x == y {
+			return 0, true
+		} else if ok {
+			return 1, true
+		}
+	}
+	return 0, false
+}
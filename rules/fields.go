@@ -0,0 +1,192 @@
+package rules
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// field reads one dotted path from a value in the form its JSON decodes
+// to: strings, float64 numbers, bools, []any and map[string]any
+type field func(reflect.Value) (any, bool)
+
+var marshalerType = reflect.TypeFor[json.Marshaler]()
+
+// fieldsFor returns a reader for every path the rules use, resolved
+// against t on first use and kept for the following checks
+func (p *Profile) fieldsFor(t reflect.Type) map[string]field {
+	if cached, ok := p.fields.Load(t); ok {
+		return cached.(map[string]field)
+	}
+	fields := map[string]field{}
+	for _, r := range p.rules {
+		for _, path := range []string{r.Field, r.Other} {
+			if _, ok := fields[path]; path != "" && !ok {
+				fields[path] = compileField(t, strings.Split(path, "."))
+			}
+		}
+	}
+	cached, _ := p.fields.LoadOrStore(t, fields)
+	return cached.(map[string]field)
+}
+
+// compileField resolves keys against the json tags of t, values with
+// their own encoding are only known through their JSON and are read that way
+func compileField(t reflect.Type, keys []string) field {
+	if t.Kind() == reflect.Interface || t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return throughJSON(keys)
+	}
+	if len(keys) == 0 {
+		return func(v reflect.Value) (any, bool) {
+			return leaf(v), true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		next := compileField(t.Elem(), keys)
+		return func(v reflect.Value) (any, bool) {
+			if v.IsNil() {
+				return nil, false
+			}
+			return next(v.Elem())
+		}
+	case reflect.Struct:
+		found, ok := jsonField(t, keys[0])
+		if !ok {
+			return missing
+		}
+		next := compileField(found.Type, keys[1:])
+		return func(v reflect.Value) (any, bool) {
+			// a nil embedded pointer leaves its fields out
+			fv, err := v.FieldByIndexErr(found.Index)
+			if err != nil || (found.omitEmpty && isEmpty(fv)) {
+				return nil, false
+			}
+			return next(fv)
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return throughJSON(keys)
+		}
+		key := reflect.ValueOf(keys[0]).Convert(t.Key())
+		next := compileField(t.Elem(), keys[1:])
+		return func(v reflect.Value) (any, bool) {
+			fv := v.MapIndex(key)
+			if !fv.IsValid() {
+				return nil, false
+			}
+			return next(fv)
+		}
+	}
+	return missing
+}
+
+func missing(reflect.Value) (any, bool) {
+	return nil, false
+}
+
+type structField struct {
+	reflect.StructField
+	omitEmpty bool
+}
+
+// jsonField finds the field encoding/json writes as name, promoted
+// fields of embedded structs included
+func jsonField(t reflect.Type, name string) (structField, bool) {
+	var best structField
+	found := false
+	for _, f := range reflect.VisibleFields(t) {
+		// embedded structs of unexported types may still have exported fields
+		if !f.IsExported() && !(f.Anonymous && indirect(f.Type).Kind() == reflect.Struct) {
+			continue
+		}
+		tag, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		// untagged embedded structs only contribute their fields
+		if f.Anonymous && tag == "" && indirect(f.Type).Kind() == reflect.Struct {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag != name || (found && len(f.Index) >= len(best.Index)) {
+			continue
+		}
+		best = structField{StructField: f, omitEmpty: strings.Contains(","+options+",", ",omitempty,")}
+		found = true
+	}
+	return best, found
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// isEmpty is encoding/json's test for leaving out an omitempty field
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+// leaf converts a scalar directly, lists and objects go through JSON
+func leaf(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return leaf(v.Elem())
+	}
+	value, _ := throughJSON(nil)(v)
+	return value
+}
+
+// throughJSON reads keys from the value's JSON form
+func throughJSON(keys []string) field {
+	return func(v reflect.Value) (any, bool) {
+		if !v.CanInterface() {
+			return nil, false
+		}
+		raw, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, false
+		}
+		var doc any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, false
+		}
+		for _, key := range keys {
+			object, ok := doc.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if doc, ok = object[key]; !ok {
+				return nil, false
+			}
+		}
+		return doc, true
+	}
+}
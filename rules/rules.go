@@ -0,0 +1,298 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rule types
+const (
+	TypeRequired   = "required"
+	TypeRegex      = "regex"
+	TypeEnum       = "enum"
+	TypeURL        = "url"
+	TypeLength     = "length"
+	TypeRange      = "range"
+	TypeCrossField = "cross-field"
+)
+
+// severities, errors reject the episode and warnings are only reported
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// cross-field operators, requires asks for the other field whenever field is set
+var operators = map[string]string{
+	"requires": "requires",
+	"eq":       "==",
+	"ne":       "!=",
+	"lt":       "<",
+	"lte":      "<=",
+	"gt":       ">",
+	"gte":      ">=",
+}
+
+// File is a rule file, in YAML or JSON:
+//
+//	profiles:
+//	  default:
+//	    rules:
+//	      - {id: EP-001, type: range, field: episodeCount, min: 1}
+//	  kids:
+//	    extends: default
+//	    rules:
+//	      - {id: KIDS-001, type: required, field: genre}
+type File struct {
+	Profiles map[string]ProfileSpec `json:"profiles" yaml:"profiles"`
+}
+
+// ProfileSpec declares a named set of rules
+type ProfileSpec struct {
+	// Extends pulls in the rules of another profile first
+	Extends string     `json:"extends" yaml:"extends"`
+	Rules   []RuleSpec `json:"rules" yaml:"rules"`
+}
+
+// RuleSpec declares one rule. Fields are JSON paths into the episode like
+// nextEpisode.channel, rules other than required skip missing fields.
+type RuleSpec struct {
+	ID    string `json:"id" yaml:"id"`
+	Type  string `json:"type" yaml:"type"`
+	Field string `json:"field" yaml:"field"`
+	// Severity is error (default) or warning
+	Severity string `json:"severity" yaml:"severity"`
+	// Message replaces the generated violation message
+	Message string `json:"message" yaml:"message"`
+	// Pattern is the regular expression of regex rules
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Values are the allowed values of enum rules
+	Values []string `json:"values" yaml:"values"`
+	// Min and Max bound length and range rules
+	Min *float64 `json:"min" yaml:"min"`
+	Max *float64 `json:"max" yaml:"max"`
+	// Op and Other compare field with another field in cross-field rules
+	Op    string `json:"op" yaml:"op"`
+	Other string `json:"other" yaml:"other"`
+}
+
+// Violation is a rule an episode broke
+type Violation struct {
+	RuleID   string
+	Field    string
+	Severity string
+	Message  string
+}
+
+// Set holds the compiled profiles
+type Set struct {
+	profiles map[string]*Profile
+}
+
+// Profile is a compiled list of rules
+type Profile struct {
+	Name  string
+	rules []*rule
+	// fields holds the field readers of each checked type, see fieldsFor
+	fields sync.Map
+}
+
+type rule struct {
+	RuleSpec
+	pattern *regexp.Regexp
+}
+
+// Profiles are the rule profiles requests can select, empty until a rule
+// file is loaded
+var Profiles = &Set{profiles: map[string]*Profile{}}
+
+// Load compiles a rule file, .json files are read as JSON and anything
+// else as YAML
+func Load(path string) (*Set, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(raw, &file)
+	} else {
+		err = yaml.Unmarshal(raw, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules %s: %w", path, err)
+	}
+	return Compile(file)
+}
+
+// Compile checks every rule and resolves profile inheritance,
+// rule ids must be unique within a profile
+func Compile(file File) (*Set, error) {
+	set := &Set{profiles: map[string]*Profile{}}
+	var resolve func(name string, seen []string) (*Profile, error)
+	resolve = func(name string, seen []string) (*Profile, error) {
+		if profile, ok := set.profiles[name]; ok {
+			return profile, nil
+		}
+		if slices.Contains(seen, name) {
+			return nil, fmt.Errorf("profile %s extends itself through %s", name, strings.Join(seen, " -> "))
+		}
+		spec, ok := file.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %s extends unknown profile %s", seen[len(seen)-1], name)
+		}
+
+		profile := &Profile{Name: name}
+		if spec.Extends != "" {
+			parent, err := resolve(spec.Extends, append(seen, name))
+			if err != nil {
+				return nil, err
+			}
+			profile.rules = slices.Clone(parent.rules)
+		}
+		for _, ruleSpec := range spec.Rules {
+			compiled, err := compileRule(ruleSpec)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+			if slices.ContainsFunc(profile.rules, func(r *rule) bool { return r.ID == compiled.ID }) {
+				return nil, fmt.Errorf("profile %s: duplicate rule id %s", name, compiled.ID)
+			}
+			profile.rules = append(profile.rules, compiled)
+		}
+		set.profiles[name] = profile
+		return profile, nil
+	}
+
+	for name := range file.Profiles {
+		if _, err := resolve(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func compileRule(spec RuleSpec) (*rule, error) {
+	if spec.ID == "" {
+		return nil, fmt.Errorf("rule on %q has no id", spec.Field)
+	}
+	if spec.Field == "" {
+		return nil, fmt.Errorf("rule %s has no field", spec.ID)
+	}
+	switch spec.Severity {
+	case "":
+		spec.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return nil, fmt.Errorf("rule %s: severity must be %s or %s", spec.ID, SeverityError, SeverityWarning)
+	}
+
+	compiled := &rule{RuleSpec: spec}
+	switch spec.Type {
+	case TypeRequired, TypeURL:
+	case TypeRegex:
+		pattern, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", spec.ID, err)
+		}
+		compiled.pattern = pattern
+	case TypeEnum:
+		if len(spec.Values) == 0 {
+			return nil, fmt.Errorf("rule %s: enum needs values", spec.ID)
+		}
+	case TypeLength, TypeRange:
+		if spec.Min == nil && spec.Max == nil {
+			return nil, fmt.Errorf("rule %s: %s needs min or max", spec.ID, spec.Type)
+		}
+		if spec.Min != nil && spec.Max != nil && *spec.Min > *spec.Max {
+			return nil, fmt.Errorf("rule %s: min is greater than max", spec.ID)
+		}
+	case TypeCrossField:
+		if _, ok := operators[spec.Op]; !ok {
+			return nil, fmt.Errorf("rule %s: op must be one of %s", spec.ID, strings.Join(operatorNames(), ", "))
+		}
+		if spec.Other == "" {
+			return nil, fmt.Errorf("rule %s: cross-field needs other", spec.ID)
+		}
+	default:
+		return nil, fmt.Errorf("rule %s: unknown type %q", spec.ID, spec.Type)
+	}
+	return compiled, nil
+}
+
+func operatorNames() []string {
+	var names []string
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile
+func (s *Set) Profile(name string) (*Profile, bool) {
+	profile, ok := s.profiles[name]
+	return profile, ok
+}
+
+// Names lists the profiles in alphabetical order
+func (s *Set) Names() []string {
+	var names []string
+	for name := range s.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RuleIDs lists the rules of the profile in evaluation order
+func (p *Profile) RuleIDs() []string {
+	ids := make([]string, len(p.rules))
+	for i, r := range p.rules {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+// Check evaluates every rule against v, whose fields are read as its JSON
+// form has them
+func (p *Profile) Check(v any) ([]Violation, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if kind := value.Kind(); kind != reflect.Struct && kind != reflect.Map && !value.Type().Implements(marshalerType) {
+		return nil, fmt.Errorf("rules only apply to objects, got %T", v)
+	}
+	fields := p.fieldsFor(value.Type())
+	get := func(path string) (any, bool) {
+		read, ok := fields[path](value)
+		if !ok {
+			return nil, false
+		}
+		return present(read)
+	}
+
+	var violations []Violation
+	for _, r := range p.rules {
+		message, ok := r.check(get)
+		if ok {
+			continue
+		}
+		if r.Message != "" {
+			message = r.Message
+		}
+		violations = append(violations, Violation{RuleID: r.ID, Field: r.Field, Severity: r.Severity, Message: message})
+	}
+	return violations, nil
+}
@@ -0,0 +1,205 @@
+package rules
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type next struct {
+	Channel string `json:"channel"`
+	Date    string `json:"date"`
+	URL     string `json:"url"`
+}
+
+type show struct {
+	Slug         string   `json:"slug"`
+	Genre        string   `json:"genre"`
+	Description  string   `json:"description"`
+	EpisodeCount int      `json:"episodeCount"`
+	Seasons      []string `json:"seasons"`
+	Next         *next    `json:"nextEpisode"`
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestRuleTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     RuleSpec
+		show     show
+		expected string
+	}{
+		{name: "Required present", rule: RuleSpec{Type: TypeRequired, Field: "genre"}, show: show{Genre: "kids"}},
+		{name: "Required missing", rule: RuleSpec{Type: TypeRequired, Field: "genre"}, expected: "genre is required"},
+		{name: "Required nested missing", rule: RuleSpec{Type: TypeRequired, Field: "nextEpisode.channel"}, show: show{Next: &next{}}, expected: "nextEpisode.channel is required"},
+		{name: "Regex match", rule: RuleSpec{Type: TypeRegex, Field: "slug", Pattern: "^show/"}, show: show{Slug: "show/a"}},
+		{name: "Regex mismatch", rule: RuleSpec{Type: TypeRegex, Field: "slug", Pattern: "^show/"}, show: show{Slug: "movie/a"}, expected: "slug must match ^show/"},
+		{name: "Regex skips missing field", rule: RuleSpec{Type: TypeRegex, Field: "slug", Pattern: "^show/"}},
+		{name: "Enum", rule: RuleSpec{Type: TypeEnum, Field: "genre", Values: []string{"kids", "family"}}, show: show{Genre: "drama"}, expected: "genre must be one of kids, family"},
+		{name: "Enum on a number", rule: RuleSpec{Type: TypeEnum, Field: "episodeCount", Values: []string{"6", "12"}}, show: show{EpisodeCount: 12}},
+		{name: "URL", rule: RuleSpec{Type: TypeURL, Field: "nextEpisode.url"}, show: show{Next: &next{URL: "go.ninemsn.com.au"}}, expected: "nextEpisode.url must be a valid URL"},
+		{name: "Length of a string", rule: RuleSpec{Type: TypeLength, Field: "description", Max: float(5)}, show: show{Description: "Très bien"}, expected: "description length must be at most 5"},
+		{name: "Length of a list", rule: RuleSpec{Type: TypeLength, Field: "seasons", Min: float(2)}, show: show{Seasons: []string{"s1", "s2"}}},
+		{name: "Range", rule: RuleSpec{Type: TypeRange, Field: "episodeCount", Min: float(1), Max: float(100)}, show: show{EpisodeCount: 101}, expected: "episodeCount must be between 1 and 100"},
+		{name: "Cross-field requires", rule: RuleSpec{Type: TypeCrossField, Field: "nextEpisode.channel", Op: "requires", Other: "nextEpisode.date"}, show: show{Next: &next{Channel: "GO!"}}, expected: "nextEpisode.channel requires nextEpisode.date"},
+		{name: "Cross-field requires without field", rule: RuleSpec{Type: TypeCrossField, Field: "nextEpisode.channel", Op: "requires", Other: "nextEpisode.date"}, show: show{Next: &next{}}},
+		{name: "Cross-field comparison", rule: RuleSpec{Type: TypeCrossField, Field: "slug", Op: "ne", Other: "genre"}, show: show{Slug: "kids", Genre: "kids"}, expected: "slug must be != genre"},
+		{name: "Cross-field dates", rule: RuleSpec{Type: TypeCrossField, Field: "nextEpisode.date", Op: "gt", Other: "nextEpisode.channel"}, show: show{Next: &next{Date: "2024-03-01T10:00:00+11:00", Channel: "2024-03-01T00:00:00Z"}}, expected: "nextEpisode.date must be > nextEpisode.channel"},
+		{name: "Custom message", rule: RuleSpec{Type: TypeRequired, Field: "genre", Message: "kids shows need a genre"}, expected: "kids shows need a genre"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.ID = "R-1"
+			set, err := Compile(File{Profiles: map[string]ProfileSpec{"test": {Rules: []RuleSpec{tt.rule}}}})
+			assert.NoError(t, err)
+			profile, _ := set.Profile("test")
+
+			violations, err := profile.Check(tt.show)
+			assert.NoError(t, err)
+			if tt.expected == "" {
+				assert.Empty(t, violations)
+				return
+			}
+			assert.Equal(t, []Violation{{RuleID: "R-1", Field: tt.rule.Field, Severity: SeverityError, Message: tt.expected}}, violations)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     File
+		expected string
+	}{
+		{name: "Missing id", file: File{Profiles: map[string]ProfileSpec{"p": {Rules: []RuleSpec{{Type: TypeRequired, Field: "genre"}}}}}, expected: `rule on "genre" has no id`},
+		{name: "Unknown type", file: File{Profiles: map[string]ProfileSpec{"p": {Rules: []RuleSpec{{ID: "A", Type: "magic", Field: "genre"}}}}}, expected: `rule A: unknown type "magic"`},
+		{name: "Bad regex", file: File{Profiles: map[string]ProfileSpec{"p": {Rules: []RuleSpec{{ID: "A", Type: TypeRegex, Field: "slug", Pattern: "("}}}}}, expected: "rule A: error parsing regexp"},
+		{name: "Range without bounds", file: File{Profiles: map[string]ProfileSpec{"p": {Rules: []RuleSpec{{ID: "A", Type: TypeRange, Field: "episodeCount"}}}}}, expected: "range needs min or max"},
+		{name: "Unknown operator", file: File{Profiles: map[string]ProfileSpec{"p": {Rules: []RuleSpec{{ID: "A", Type: TypeCrossField, Field: "a", Op: "like", Other: "b"}}}}}, expected: "op must be one of eq, gt, gte, lt, lte, ne, requires"},
+		{name: "Duplicate id", file: File{Profiles: map[string]ProfileSpec{
+			"base":  {Rules: []RuleSpec{{ID: "A", Type: TypeRequired, Field: "genre"}}},
+			"child": {Extends: "base", Rules: []RuleSpec{{ID: "A", Type: TypeRequired, Field: "slug"}}},
+		}}, expected: "duplicate rule id A"},
+		{name: "Unknown parent", file: File{Profiles: map[string]ProfileSpec{"p": {Extends: "nope"}}}, expected: "profile p extends unknown profile nope"},
+		{name: "Inheritance cycle", file: File{Profiles: map[string]ProfileSpec{"a": {Extends: "b"}, "b": {Extends: "a"}}}, expected: "extends itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.file)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	set, err := Load("testdata/rules.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "kids"}, set.Names())
+
+	kids, ok := set.Profile("kids")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"EP-001", "EP-002", "KIDS-001", "KIDS-002", "KIDS-003", "KIDS-004"}, kids.RuleIDs())
+
+	violations, err := kids.Check(show{Genre: "kids", Description: "Short", EpisodeCount: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []Violation{{RuleID: "KIDS-004", Field: "description", Severity: SeverityWarning, Message: "description length must be between 10 and 300"}}, violations)
+
+	set, err = Load("testdata/rules.json")
+	assert.NoError(t, err)
+	strict, ok := set.Profile("strict")
+	assert.True(t, ok)
+	violations, err = strict.Check(show{Slug: "show/the-taste"})
+	assert.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "S-001", violations[0].RuleID)
+
+	_, err = Load("testdata/missing.yaml")
+	assert.Error(t, err)
+}
+
+type Audit struct {
+	Editor string `json:"editor"`
+}
+
+type listing struct {
+	Audit
+	*next    `json:"next"`
+	Rank     int               `json:"rank,omitempty"`
+	Live     bool              `json:"live"`
+	Airs     time.Time         `json:"airs"`
+	Labels   map[string]string `json:"labels"`
+	Show     *show             `json:"show"`
+	Internal string            `json:"-"`
+}
+
+// TestCheckReadsJSONForm checks fields are read as the value's JSON has them
+func TestCheckReadsJSONForm(t *testing.T) {
+	paths := []string{"editor", "Audit", "next.channel", "rank", "live", "airs", "labels.tier", "labels",
+		"show.nextEpisode.url", "show.seasons", "show.episodeCount", "Internal", "unknown.key"}
+	values := []listing{
+		{},
+		{Audit: Audit{Editor: "sam"}, next: &next{Channel: "GO!"}, Rank: 2, Live: true,
+			Airs: time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC), Labels: map[string]string{"tier": "gold"},
+			Show: &show{Seasons: []string{"s1"}, EpisodeCount: 0, Next: &next{URL: "http://go.ninemsn.com.au/"}}, Internal: "x"},
+	}
+
+	var specs []RuleSpec
+	for i, path := range paths {
+		specs = append(specs, RuleSpec{ID: path, Type: TypeRequired, Field: path},
+			RuleSpec{ID: path + "-enum", Type: TypeEnum, Field: path, Values: []string{"sam", "2", "true", "gold", "0", "2024-03-01T20:30:00Z"}},
+			RuleSpec{ID: path + "-eq", Type: TypeCrossField, Field: path, Op: "eq", Other: paths[(i+1)%len(paths)]})
+	}
+	set, err := Compile(File{Profiles: map[string]ProfileSpec{"all": {Rules: specs}}})
+	assert.NoError(t, err)
+	profile, _ := set.Profile("all")
+
+	for _, value := range values {
+		// the profile is checked twice to go through its cached fields
+		for range 2 {
+			violations, err := profile.Check(&value)
+			assert.NoError(t, err)
+
+			raw, err := json.Marshal(value)
+			assert.NoError(t, err)
+			var doc map[string]any
+			assert.NoError(t, json.Unmarshal(raw, &doc))
+			var expected []Violation
+			for _, r := range profile.rules {
+				if message, ok := r.check(func(path string) (any, bool) { return lookupJSON(doc, path) }); !ok {
+					expected = append(expected, Violation{RuleID: r.ID, Field: r.Field, Severity: r.Severity, Message: message})
+				}
+			}
+			assert.Equal(t, expected, violations, string(raw))
+		}
+	}
+
+	_, err = profile.Check("not an object")
+	assert.Error(t, err)
+	violations, err := profile.Check(map[string]any{"editor": "sam"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, violations)
+}
+
+// lookupJSON reads a dotted path from a decoded JSON document
+func lookupJSON(doc map[string]any, path string) (any, bool) {
+	var value any = doc
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return present(value)
+}
@@ -0,0 +1,221 @@
+package sanitize
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Policy is an allow-list of elements and the attributes each may carry.
+// Anything not listed is removed, URL attributes must use a safe scheme.
+type Policy struct {
+	// Elements maps a tag to its allowed attributes
+	Elements map[string][]string
+	// GlobalAttrs are allowed on every allowed element
+	GlobalAttrs []string
+	// URLAttrs hold URLs and are checked against Schemes
+	URLAttrs []string
+	// Schemes are the allowed URL schemes, relative URLs are always allowed
+	Schemes []string
+}
+
+// dropWithContent are removed together with everything inside them
+var dropWithContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "svg": true, "math": true, "textarea": true,
+}
+
+// blockElements separate words when markup is flattened to text
+var blockElements = map[string]bool{
+	"br": true, "p": true, "div": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "tr": true, "td": true,
+}
+
+// Default is the policy for the markup our web client renders
+var Default = &Policy{
+	Elements: map[string][]string{
+		"a":      {"href"},
+		"b":      nil,
+		"strong": nil,
+		"i":      nil,
+		"em":     nil,
+		"u":      nil,
+		"small":  nil,
+		"sub":    nil,
+		"sup":    nil,
+		"br":     nil,
+		"p":      nil,
+		"span":   nil,
+		"div":    nil,
+		"ul":     nil,
+		"ol":     nil,
+		"li":     nil,
+	},
+	GlobalAttrs: []string{"class", "title"},
+	URLAttrs:    []string{"href"},
+	Schemes:     []string{"http", "https", "mailto"},
+}
+
+// Sanitize removes everything the policy doesn't allow, what it keeps
+// untouched is written back as it was sent.
+// modified reports whether anything had to be removed.
+func (p *Policy) Sanitize(s string) (clean string, modified bool) {
+	var sb strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(s))
+	// depth of an element being dropped together with its content
+	skipping := ""
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				modified = true
+			}
+			return sb.String(), modified
+		}
+		// Raw is only valid until the token is read
+		raw := string(tokenizer.Raw())
+		token := tokenizer.Token()
+
+		if skipping != "" {
+			switch {
+			case tt == xhtml.StartTagToken && token.Data == skipping:
+				skipDepth++
+			case tt == xhtml.EndTagToken && token.Data == skipping:
+				skipDepth--
+				if skipDepth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case xhtml.TextToken:
+			// a bare < could open a tag once whatever followed it is removed
+			if strings.Contains(raw, "<") {
+				sb.WriteString(html.EscapeString(token.Data))
+			} else {
+				sb.WriteString(raw)
+			}
+
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if dropWithContent[token.Data] {
+				modified = true
+				if tt == xhtml.StartTagToken {
+					skipping, skipDepth = token.Data, 1
+				}
+				continue
+			}
+			allowed, ok := p.Elements[token.Data]
+			if !ok {
+				modified = true
+				continue
+			}
+			if !slices.ContainsFunc(token.Attr, func(attr xhtml.Attribute) bool { return !p.allowAttr(allowed, attr) }) {
+				sb.WriteString(raw)
+				continue
+			}
+			modified = true
+			sb.WriteByte('<')
+			sb.WriteString(token.Data)
+			for _, attr := range token.Attr {
+				if !p.allowAttr(allowed, attr) {
+					continue
+				}
+				sb.WriteByte(' ')
+				sb.WriteString(attr.Key)
+				sb.WriteString(`="`)
+				sb.WriteString(html.EscapeString(attr.Val))
+				sb.WriteByte('"')
+			}
+			if tt == xhtml.SelfClosingTagToken {
+				sb.WriteByte('/')
+			}
+			sb.WriteByte('>')
+
+		case xhtml.EndTagToken:
+			if _, ok := p.Elements[token.Data]; !ok {
+				modified = true
+				continue
+			}
+			sb.WriteString(raw)
+
+		default:
+			// comments and doctypes have no place in a fragment
+			modified = true
+		}
+	}
+}
+
+// Safe reports whether s passes the policy untouched
+func (p *Policy) Safe(s string) bool {
+	_, modified := p.Sanitize(s)
+	return !modified
+}
+
+func (p *Policy) allowAttr(allowed []string, attr xhtml.Attribute) bool {
+	if attr.Namespace != "" {
+		return false
+	}
+	if !slices.Contains(allowed, attr.Key) && !slices.Contains(p.GlobalAttrs, attr.Key) {
+		return false
+	}
+	if slices.Contains(p.URLAttrs, attr.Key) {
+		return p.safeURL(attr.Val)
+	}
+	return true
+}
+
+func (p *Policy) safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		// relative URLs can't switch to javascript: or data:
+		return !strings.Contains(u.Path, ":")
+	}
+	return slices.Contains(p.Schemes, strings.ToLower(u.Scheme))
+}
+
+// Text strips all markup and returns the readable text, entities decoded
+// and whitespace collapsed
+func Text(s string) string {
+	var parts []string
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(s))
+	skipping := ""
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			return strings.Join(strings.Fields(strings.Join(parts, "")), " ")
+		}
+		token := tokenizer.Token()
+
+		switch {
+		case skipping != "":
+			if tt == xhtml.StartTagToken && token.Data == skipping {
+				skipDepth++
+			} else if tt == xhtml.EndTagToken && token.Data == skipping {
+				skipDepth--
+				if skipDepth == 0 {
+					skipping = ""
+				}
+			}
+		case tt == xhtml.StartTagToken && dropWithContent[token.Data]:
+			skipping, skipDepth = token.Data, 1
+		case tt == xhtml.TextToken:
+			parts = append(parts, token.Data)
+		case blockElements[token.Data]:
+			// block boundaries separate words
+			parts = append(parts, " ")
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expected     string
+		wantModified bool
+	}{
+		{
+			name:     "Feed markup passes untouched",
+			input:    `<br><span class="visit">Visit the Official Website</span></span>`,
+			expected: `<br><span class="visit">Visit the Official Website</span></span>`,
+		},
+		{
+			name:     "Plain text",
+			input:    "What's life like?",
+			expected: "What's life like?",
+		},
+		{
+			name:         "Kept text and attributes are written back as sent",
+			input:        `<span title='Mum &amp; "Dad"'>What's on &amp; when</span><script>x()</script>`,
+			expected:     `<span title='Mum &amp; "Dad"'>What's on &amp; when</span>`,
+			wantModified: true,
+		},
+		{
+			name:         "Removing markup doesn't open a tag",
+			input:        `<<script></script>b onmouseover=x()>`,
+			expected:     `&lt;b onmouseover=x()>`,
+			wantModified: true,
+		},
+		{
+			name:         "Script is removed with its content",
+			input:        `<b>Hi</b><script>alert("x")</script>!`,
+			expected:     `<b>Hi</b>!`,
+			wantModified: true,
+		},
+		{
+			name:         "Event handlers are removed",
+			input:        `<span onclick="steal()" class="x">Click</span>`,
+			expected:     `<span class="x">Click</span>`,
+			wantModified: true,
+		},
+		{
+			name:         "javascript URL is removed",
+			input:        `<a href="javascript:alert(1)">Watch</a>`,
+			expected:     `<a>Watch</a>`,
+			wantModified: true,
+		},
+		{
+			name:         "Obfuscated javascript URL is removed",
+			input:        `<a href=" JaVaScRiPt:alert(1)">Watch</a>`,
+			expected:     `<a>Watch</a>`,
+			wantModified: true,
+		},
+		{
+			name:     "Safe link is kept",
+			input:    `<a href="http://go.ninemsn.com.au/">Go</a>`,
+			expected: `<a href="http://go.ninemsn.com.au/">Go</a>`,
+		},
+		{
+			name:         "Unknown element is unwrapped",
+			input:        `<marquee>Now showing</marquee>`,
+			expected:     `Now showing`,
+			wantModified: true,
+		},
+		{
+			name:         "Images and comments are removed",
+			input:        `<img src=x onerror=alert(1)><!-- hidden -->Text`,
+			expected:     `Text`,
+			wantModified: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clean, modified := Default.Sanitize(tt.input)
+			assert.Equal(t, tt.expected, clean)
+			assert.Equal(t, tt.wantModified, modified)
+			assert.Equal(t, !tt.wantModified, Default.Safe(tt.input))
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Markup is flattened",
+			input:    `<br><span class="visit">Visit the Official Website</span></span>`,
+			expected: "Visit the Official Website",
+		},
+		{
+			name:     "Entities are decoded and inline tags joined",
+			input:    `Le Go&ucirc;t <b>live</b>ly`,
+			expected: "Le Goût lively",
+		},
+		{
+			name:     "Blocks separate words",
+			input:    `<p>One</p><p>Two</p>Three<br>Four`,
+			expected: "One Two Three Four",
+		},
+		{
+			name:     "Script content is dropped",
+			input:    `Safe<script>alert(1)</script> text`,
+			expected: "Safe text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Text(tt.input))
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect we publish
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema our types need
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	ID          string             `json:"$id,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        Types              `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties applies to the members of maps, which have no
+	// declared properties
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types is a single type name or a list of them, e.g. ["object", "null"]
+type Types []string
+
+// MarshalJSON writes a lone type as a plain string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON reads a plain string or a list
+func (t *Types) UnmarshalJSON(raw []byte) error {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// Generator reflects Go types into schemas, struct types are shared
+// through $defs
+type Generator struct {
+	// Overrides give the schema of types with custom JSON encodings
+	Overrides map[reflect.Type]*Schema
+	// Enums are value lists kept in Go, a jsonschema tag "enumOf=name"
+	// takes its values from the list registered as name
+	Enums map[string][]string
+	defs  map[string]*Schema
+}
+
+// NewGenerator returns a generator which knows time.Time
+func NewGenerator() *Generator {
+	return &Generator{
+		Overrides: map[reflect.Type]*Schema{
+			reflect.TypeOf(time.Time{}): {Type: Types{"string"}, Format: "date-time"},
+		},
+		Enums: map[string][]string{},
+		defs:  map[string]*Schema{},
+	}
+}
+
+// Bundle returns a document whose root is the schema of root, every other
+// type in types is published under $defs for clients to reference
+func (g *Generator) Bundle(id, title string, root any, types ...any) *Schema {
+	bundle := g.Reflect(reflect.TypeOf(root))
+	for _, v := range types {
+		g.Reflect(reflect.TypeOf(v))
+	}
+	bundle.Schema = Draft
+	bundle.ID = id
+	bundle.Title = title
+	bundle.Defs = g.defs
+	return bundle
+}
+
+// Reflect returns the schema of t, structs are returned as a $ref
+func (g *Generator) Reflect(t reflect.Type) *Schema {
+	if override, ok := g.Overrides[t]; ok {
+		copied := *override
+		return &copied
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := g.Reflect(t.Elem())
+		if inner.Ref != "" {
+			return &Schema{AnyOf: []*Schema{inner, {Type: Types{"null"}}}}
+		}
+		if len(inner.Type) > 0 && !inner.allows("null") {
+			inner.Type = append(inner.Type, "null")
+		}
+		return inner
+	case reflect.Struct:
+		g.reflectStruct(t)
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: Types{"array", "null"}, Items: g.Reflect(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: g.Reflect(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	}
+	return &Schema{}
+}
+
+// reflectStruct adds the schema of a struct to $defs. Fields follow their
+// json tags, a jsonschema tag adds "required", "enum=a|b" and "enumOf=name"
+// separated by commas. An unregistered enumOf name panics, the schema
+// would otherwise quietly allow any value.
+func (g *Generator) reflectStruct(t reflect.Type) {
+	if _, ok := g.defs[t.Name()]; ok {
+		return
+	}
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	// registered first so recursive types terminate
+	g.defs[t.Name()] = s
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.Reflect(field.Type)
+		for _, option := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+			key, value, _ := strings.Cut(option, "=")
+			switch key {
+			case "required":
+				// a required value must be given for real
+				s.Required = append(s.Required, name)
+				property.Type = slices.DeleteFunc(property.Type, func(t string) bool { return t == "null" })
+			case "enum":
+				for _, item := range strings.Split(value, "|") {
+					property.Enum = append(property.Enum, item)
+				}
+			case "enumOf":
+				values, ok := g.Enums[value]
+				if !ok {
+					panic(fmt.Sprintf("schema: %s.%s uses unknown enum %q", t.Name(), field.Name, value))
+				}
+				for _, item := range values {
+					property.Enum = append(property.Enum, item)
+				}
+			}
+		}
+		s.Properties[name] = property
+	}
+}
+
+// allows reports whether the schema lists the type
+func (s *Schema) allows(name string) bool {
+	for _, t := range s.Type {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteRefs moves every $ref starting with from to start with to, e.g.
+// "#/$defs/" to "#/components/schemas/" when the schemas are embedded elsewhere
+func (s *Schema) RewriteRefs(from, to string) {
+	if s == nil {
+		return
+	}
+	if rest, ok := strings.CutPrefix(s.Ref, from); ok {
+		s.Ref = to + rest
+	}
+	s.Items.RewriteRefs(from, to)
+	s.AdditionalProperties.RewriteRefs(from, to)
+	for _, property := range s.Properties {
+		property.RewriteRefs(from, to)
+	}
+	for _, option := range s.AnyOf {
+		option.RewriteRefs(from, to)
+	}
+	for _, def := range s.Defs {
+		def.RewriteRefs(from, to)
+	}
+}
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type child struct {
+	Name string `json:"name" jsonschema:"required"`
+	Kind string `json:"kind" jsonschema:"enum=a|b"`
+}
+
+type parent struct {
+	ID       int       `json:"id" jsonschema:"required"`
+	Score    float64   `json:"score"`
+	Tags     []string  `json:"tags"`
+	Child    *child    `json:"child"`
+	Children []child   `json:"children,omitempty"`
+	Created  time.Time `json:"created"`
+	Next     *parent   `json:"next"`
+	Counter  *int      `json:"counter"`
+	Skipped  string    `json:"-"`
+	hidden   string    //nolint:unused
+	Labels   map[string]string
+}
+
+func TestGenerate(t *testing.T) {
+	bundle := NewGenerator().Bundle("/schema", "Test", parent{})
+
+	assert.Equal(t, Draft, bundle.Schema)
+	assert.Equal(t, "#/$defs/parent", bundle.Ref)
+	assert.ElementsMatch(t, []string{"parent", "child"}, keys(bundle.Defs))
+
+	p := bundle.Defs["parent"]
+	assert.Equal(t, []string{"id"}, p.Required)
+	assert.Equal(t, Types{"integer"}, p.Properties["id"].Type)
+	assert.Equal(t, Types{"number"}, p.Properties["score"].Type)
+	assert.Equal(t, Types{"array", "null"}, p.Properties["tags"].Type)
+	assert.Equal(t, "#/$defs/child", p.Properties["child"].AnyOf[0].Ref)
+	assert.Equal(t, "date-time", p.Properties["created"].Format)
+	assert.Equal(t, Types{"integer", "null"}, p.Properties["counter"].Type)
+	assert.Equal(t, Types{"object"}, p.Properties["Labels"].Type)
+	assert.Equal(t, Types{"string"}, p.Properties["Labels"].AdditionalProperties.Type)
+	assert.NotContains(t, p.Properties, "Skipped")
+	assert.NotContains(t, p.Properties, "hidden")
+
+	c := bundle.Defs["child"]
+	assert.Equal(t, []string{"name"}, c.Required)
+	assert.Equal(t, []any{"a", "b"}, c.Properties["kind"].Enum)
+}
+
+type sized struct {
+	Size string `json:"size" jsonschema:"enumOf=sizes"`
+}
+
+func TestGenerateNamedEnum(t *testing.T) {
+	generator := NewGenerator()
+	generator.Enums["sizes"] = []string{"small", "large"}
+	bundle := generator.Bundle("/schema", "Test", sized{})
+	assert.Equal(t, []any{"small", "large"}, bundle.Defs["sized"].Properties["size"].Enum)
+
+	assert.Panics(t, func() { NewGenerator().Bundle("/schema", "Test", sized{}) })
+}
+
+func TestTypesJSON(t *testing.T) {
+	raw, err := json.Marshal(&Schema{Type: Types{"string"}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "string"}`, string(raw))
+
+	var s Schema
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": ["array", "null"]}`), &s))
+	assert.Equal(t, Types{"array", "null"}, s.Type)
+}
+
+func TestValidate(t *testing.T) {
+	bundle := NewGenerator().Bundle("/schema", "Test", parent{})
+	raw, err := json.Marshal(bundle)
+	assert.NoError(t, err)
+	published, err := Parse(raw)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		doc      string
+		expected []Violation
+	}{
+		{name: "Valid", doc: `{"id": 1, "score": 1.5, "tags": ["x"], "child": {"name": "c", "kind": "a"}, "next": {"id": 2}}`},
+		{name: "Nulls where allowed", doc: `{"id": 1, "tags": null, "child": null, "counter": null}`},
+		{name: "Missing required", doc: `{}`, expected: []Violation{{Pointer: "/id", Message: "is required"}}},
+		{name: "Wrong type", doc: `{"id": "1"}`, expected: []Violation{{Pointer: "/id", Message: "expected integer, got string"}}},
+		{name: "Fraction for integer", doc: `{"id": 1.5}`, expected: []Violation{{Pointer: "/id", Message: "expected integer, got number"}}},
+		{name: "Integer for number", doc: `{"id": 1, "score": 2}`},
+		{name: "Integer beyond int64", doc: `{"id": 9223372036854775808}`, expected: []Violation{{Pointer: "/id", Message: "expected integer, got number"}}},
+		{name: "Integer below int64", doc: `{"id": -1e19}`, expected: []Violation{{Pointer: "/id", Message: "expected integer, got number"}}},
+		{name: "Largest int64", doc: `{"id": 9223372036854775807}`},
+		{name: "Nested", doc: `{"id": 1, "children": [{"name": "a"}, {"kind": "c"}]}`, expected: []Violation{
+			{Pointer: "/children/1/name", Message: "is required"},
+			{Pointer: "/children/1/kind", Message: "must be one of a, b"},
+		}},
+		{name: "Wrong type for an object", doc: `{"id": 1, "child": 5}`, expected: []Violation{{Pointer: "/child", Message: "expected object, got integer"}}},
+		{name: "Recursive", doc: `{"id": 1, "next": {"id": 2, "next": {"tags": [1]}}}`, expected: []Violation{
+			{Pointer: "/next/next/id", Message: "is required"},
+			{Pointer: "/next/next/tags/0", Message: "expected string, got integer"},
+		}},
+		{name: "Map members", doc: `{"id": 1, "Labels": {"a": "x", "b": 2}}`, expected: []Violation{
+			{Pointer: "/Labels/b", Message: "expected string, got integer"},
+		}},
+		{name: "Root type", doc: `[]`, expected: []Violation{{Pointer: "", Message: "expected object, got array"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := published.ValidateJSON([]byte(tt.doc))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, violations)
+		})
+	}
+
+	_, err = published.ValidateJSON([]byte(`{"id": `))
+	assert.Error(t, err)
+}
+
+func TestEscapePointer(t *testing.T) {
+	assert.Equal(t, "a~1b~0c", escape("a/b~c"))
+}
+
+func keys(m map[string]*Schema) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Violation is a place where a document breaks the schema
+type Violation struct {
+	// Pointer is the RFC 6901 JSON pointer of the offending value
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// Parse reads a published schema
+func Parse(raw []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return &s, nil
+}
+
+// ValidateJSON checks a raw JSON document, the error is set when it isn't JSON at all
+func (s *Schema) ValidateJSON(raw []byte) ([]Violation, error) {
+	return s.ValidateJSONRef("", raw)
+}
+
+// ValidateJSONRef checks a raw JSON document against the definition ref
+// points at, e.g. "#/$defs/Episode", an empty ref means the root
+func (s *Schema) ValidateJSONRef(ref string, raw []byte) ([]Violation, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		return s.Validate(doc), nil
+	}
+	v := &validator{root: s}
+	v.validate(&Schema{Ref: ref}, doc, "")
+	return v.violations, nil
+}
+
+// Validate checks a document decoded with json.Decoder.UseNumber, s must be
+// the root of the bundle so $refs resolve
+func (s *Schema) Validate(doc any) []Violation {
+	v := &validator{root: s}
+	v.validate(s, doc, "")
+	return v.violations
+}
+
+type validator struct {
+	root       *Schema
+	violations []Violation
+}
+
+func (v *validator) fail(pointer, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(s *Schema, doc any, pointer string) {
+	if s.Ref != "" {
+		target := v.resolve(s.Ref)
+		if target == nil {
+			v.fail(pointer, "unresolvable $ref %s", s.Ref)
+			return
+		}
+		v.validate(target, doc, pointer)
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, option := range s.AnyOf {
+			sub := &validator{root: v.root}
+			sub.validate(option, doc, pointer)
+			if len(sub.violations) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			// report against the first option, it is the non null one for our types
+			v.validate(s.AnyOf[0], doc, pointer)
+		}
+	}
+
+	if len(s.Type) > 0 {
+		actual := typeOf(doc)
+		if !s.allows(actual) && !(actual == "integer" && s.allows("number")) {
+			v.fail(pointer, "expected %s, got %s", strings.Join(s.Type, " or "), actual)
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, doc) {
+		v.fail(pointer, "must be one of %s", enumList(s.Enum))
+	}
+
+	switch value := doc.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				v.fail(pointer+"/"+escape(name), "is required")
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		// stable reports
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				v.validate(property, value[name], pointer+"/"+escape(name))
+			} else if s.AdditionalProperties != nil {
+				v.validate(s.AdditionalProperties, value[name], pointer+"/"+escape(name))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range value {
+				v.validate(s.Items, item, pointer+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+}
+
+// resolve follows a local #/$defs/Name reference
+func (v *validator) resolve(ref string) *Schema {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil
+	}
+	return v.root.Defs[name]
+}
+
+// typeOf names the JSON type of a value decoded with UseNumber, numbers
+// are integers when they fit an int64
+func typeOf(doc any) string {
+	switch value := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func inEnum(enum []any, doc any) bool {
+	for _, item := range enum {
+		if fmt.Sprint(item) == fmt.Sprint(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	items := make([]string, len(enum))
+	for i, item := range enum {
+		items[i] = fmt.Sprint(item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// escape encodes a property name as a JSON pointer token
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
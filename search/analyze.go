@@ -0,0 +1,53 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold lower cases text and strips diacritics, so "Goût" and "gout" compare equal
+func Fold(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return norm.NFC.String(b.String())
+}
+
+// Tokenize splits folded text into words. Apostrophes inside a word are
+// dropped rather than splitting it, "kid's" is one word.
+func Tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range Fold(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case (r == '\'' || r == '’') && word.Len() > 0:
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Analyze turns text into the terms the index holds, in order
+func Analyze(text string) []string {
+	tokens := Tokenize(text)
+	for i, token := range tokens {
+		tokens[i] = Stem(token)
+	}
+	return tokens
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "gout", Fold("Goût"))
+	assert.Equal(t, "creme brulee", Fold("CRÈME BRÛLÉE"))
+	assert.Equal(t, "sao paulo", Fold("São Paulo"))
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"Words and punctuation", "Hello, World!", []string{"hello", "world"}},
+		{"Apostrophes stay inside words", "Kid's Club — the kids’ show", []string{"kids", "club", "the", "kids", "show"}},
+		{"Digits", "Top Gear 2024", []string{"top", "gear", "2024"}},
+		{"Diacritics", "Le Goût", []string{"le", "gout"}},
+		{"Nothing", " -- ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Tokenize(tt.text))
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	assert.Equal(t, []string{"footbal", "team"}, Analyze("Football Teams"))
+}
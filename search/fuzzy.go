@@ -0,0 +1,216 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Distance is the number of single character insertions, deletions,
+// substitutions and swaps of neighbours turning a into b
+func Distance(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	// three rows are enough, swaps look two back
+	prev2 := make([]int, len(y)+1)
+	prev := make([]int, len(y)+1)
+	row := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		row[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				row[j] = min(row[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, row = prev, row, prev2
+	}
+	return prev[len(y)]
+}
+
+// Trigrams returns the distinct three letter sequences of folded text,
+// padded so that short words and word starts count too
+func Trigrams(text string) []string {
+	var grams []string
+	for _, word := range Tokenize(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			gram := string(runes[i : i+3])
+			if !slices.Contains(grams, gram) {
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// FuzzyOptions bound how far a match may be from what was asked for
+type FuzzyOptions struct {
+	// MaxDistance is the most edits a match may need
+	MaxDistance int
+	// MinSimilarity is the lowest score a match may have, from 0 to 1
+	MinSimilarity float64
+	// Prefix also matches keys starting with something close to the text,
+	// for autocomplete
+	Prefix bool
+	// Limit caps the number of matches, 0 returns all
+	Limit int
+}
+
+// FuzzyMatch is a document with a key close to the text searched for
+type FuzzyMatch struct {
+	Doc int
+	// Key is the key of the document which matched best
+	Key      string
+	Distance int
+	// Score is 1 for an exact match, falling with the edits needed
+	// relative to the length of the text
+	Score float64
+}
+
+// FuzzyIndex finds documents by keys with typos in them. A trigram index
+// narrows the documents down before edit distances are computed.
+type FuzzyIndex struct {
+	// keys holds the keys of every document as given and folded
+	keys   [][]string
+	folded [][]string
+	grams  map[string][]int
+}
+
+// NewFuzzyIndex returns an empty fuzzy index
+func NewFuzzyIndex() *FuzzyIndex {
+	return &FuzzyIndex{grams: map[string][]int{}}
+}
+
+// Len returns the number of documents
+func (idx *FuzzyIndex) Len() int {
+	return len(idx.keys)
+}
+
+// Add indexes a document by its keys, like a title and a slug. It
+// returns the document's number.
+func (idx *FuzzyIndex) Add(keys ...string) int {
+	doc := len(idx.keys)
+	var folded []string
+	var grams []string
+	for _, key := range keys {
+		folded = append(folded, strings.Join(Tokenize(key), " "))
+		for _, gram := range Trigrams(key) {
+			if !slices.Contains(grams, gram) {
+				grams = append(grams, gram)
+			}
+		}
+	}
+	for _, gram := range grams {
+		idx.grams[gram] = append(idx.grams[gram], doc)
+	}
+	idx.keys = append(idx.keys, keys)
+	idx.folded = append(idx.folded, folded)
+	return doc
+}
+
+// Match returns the documents with a key within the options of text, the
+// best first and ties in document order
+func (idx *FuzzyIndex) Match(text string, opts FuzzyOptions) []FuzzyMatch {
+	query := strings.Join(Tokenize(text), " ")
+	if query == "" {
+		return []FuzzyMatch{}
+	}
+
+	matches := []FuzzyMatch{}
+	for _, doc := range idx.candidates(text, opts) {
+		best := FuzzyMatch{Doc: doc, Distance: -1}
+		for i, key := range idx.folded[doc] {
+			distance := keyDistance(query, key, opts.Prefix)
+			if best.Distance < 0 || distance < best.Distance {
+				best.Key, best.Distance = idx.keys[doc][i], distance
+			}
+		}
+		if best.Distance < 0 || best.Distance > opts.MaxDistance {
+			continue
+		}
+		best.Score = 1 - float64(best.Distance)/float64(len([]rune(query)))
+		if best.Score < opts.MinSimilarity {
+			continue
+		}
+		matches = append(matches, best)
+	}
+
+	slices.SortFunc(matches, func(a, b FuzzyMatch) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Doc, b.Doc)
+	})
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches
+}
+
+// candidates returns the documents which share enough trigrams with text
+// to be within MaxDistance of it. An edit changes at most four trigrams,
+// a swap of neighbours does. With Prefix the last word may be unfinished,
+// so its closing gram, like "ar " of "thunderbirds ar", need not be in the
+// key either. When that could be all of them every document is a candidate.
+func (idx *FuzzyIndex) candidates(text string, opts FuzzyOptions) []int {
+	grams := Trigrams(text)
+	needed := len(grams) - 4*opts.MaxDistance
+	if opts.Prefix {
+		needed--
+	}
+	if needed <= 0 {
+		docs := make([]int, idx.Len())
+		for doc := range docs {
+			docs[doc] = doc
+		}
+		return docs
+	}
+
+	shared := map[int]int{}
+	for _, gram := range grams {
+		for _, doc := range idx.grams[gram] {
+			shared[doc]++
+		}
+	}
+	var docs []int
+	for doc, n := range shared {
+		if n >= needed {
+			docs = append(docs, doc)
+		}
+	}
+	slices.Sort(docs)
+	return docs
+}
+
+// keyDistance compares the query with the whole key and with every run
+// of as many words in it, so "thunderbrds" finds "Thunderbirds Are Go".
+// With prefix the last word may be unfinished.
+func keyDistance(query, key string, prefix bool) int {
+	best := Distance(query, key)
+	queryWords := strings.Fields(query)
+	keyWords := strings.Fields(key)
+	for start := 0; start+len(queryWords) <= len(keyWords); start++ {
+		window := keyWords[start : start+len(queryWords)]
+		best = min(best, Distance(query, strings.Join(window, " ")))
+		if prefix {
+			last := []rune(window[len(window)-1])
+			head := strings.Join(window[:len(window)-1], " ")
+			if head != "" {
+				head += " "
+			}
+			lastQuery := []rune(queryWords[len(queryWords)-1])
+			// cut the last word near the length typed so far
+			for n := max(1, len(lastQuery)-1); n <= min(len(last), len(lastQuery)+1); n++ {
+				best = min(best, Distance(query, head+string(last[:n])))
+			}
+		}
+	}
+	return best
+}
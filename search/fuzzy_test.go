@@ -0,0 +1,149 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"thunderbirds", "thunderbrds", 1},
+		{"taste", "tsate", 1},
+		{"goût", "gout", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Distance(tt.a, tt.b))
+			assert.Equal(t, tt.expected, Distance(tt.b, tt.a))
+		})
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, []string{"  g", " go", "gou", "out", "ut "}, Trigrams("Goût"))
+	assert.Empty(t, Trigrams("!!"))
+}
+
+func fuzzyIndex() *FuzzyIndex {
+	idx := NewFuzzyIndex()
+	idx.Add("Thunderbirds", "show/thunderbirds")
+	idx.Add("Thunderbirds Are Go", "show/thunderbirdsarego")
+	idx.Add("The Taste", "show/thetaste")
+	idx.Add("Le Goût", "show/legout")
+	idx.Add("Underbelly", "show/underbelly")
+	return idx
+}
+
+func matchedDocs(matches []FuzzyMatch) []int {
+	out := []int{}
+	for _, match := range matches {
+		out = append(out, match.Doc)
+	}
+	return out
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	idx := fuzzyIndex()
+
+	tests := []struct {
+		name     string
+		text     string
+		opts     FuzzyOptions
+		expected []int
+	}{
+		{"Exact", "The Taste", FuzzyOptions{MaxDistance: 2}, []int{2}},
+		{"Typo", "thunderbrds", FuzzyOptions{MaxDistance: 2}, []int{0, 1}},
+		{"Swapped letters", "teh tsate", FuzzyOptions{MaxDistance: 2}, []int{2}},
+		{"Accents are ignored", "le gout", FuzzyOptions{MaxDistance: 0}, []int{3}},
+		{"Slugs are keys", "legout", FuzzyOptions{MaxDistance: 1}, []int{3}},
+		{"Too far", "thunder", FuzzyOptions{MaxDistance: 2}, []int{}},
+		{"Prefix", "thunder", FuzzyOptions{MaxDistance: 1, Prefix: true}, []int{0, 1}},
+		{"Prefix with a typo", "undrb", FuzzyOptions{MaxDistance: 1, Prefix: true}, []int{4}},
+		{"Similarity threshold", "tste", FuzzyOptions{MaxDistance: 2, MinSimilarity: 0.9}, []int{}},
+		{"Limit", "thunderbirds", FuzzyOptions{MaxDistance: 2, Limit: 1}, []int{0}},
+		{"Nothing to match", "!!", FuzzyOptions{MaxDistance: 2}, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchedDocs(idx.Match(tt.text, tt.opts)))
+		})
+	}
+}
+
+func TestFuzzyMatchScores(t *testing.T) {
+	matches := fuzzyIndex().Match("thunderbrds", FuzzyOptions{MaxDistance: 2})
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "Thunderbirds", matches[0].Key)
+	assert.Equal(t, 1, matches[0].Distance)
+	assert.InDelta(t, 1-1.0/11, matches[0].Score, 1e-9)
+
+	exact := fuzzyIndex().Match("le gout", FuzzyOptions{})
+	assert.Equal(t, 1.0, exact[0].Score)
+}
+
+// TestFuzzyCandidatesMissNothing checks the trigram filter against a scan
+// of every document, with single typos anywhere in a title and the title
+// cut short the way autocomplete sends it
+func TestFuzzyCandidatesMissNothing(t *testing.T) {
+	titles := []string{"Thunderbirds", "Thunderbirds Are Go", "The Taste", "Le Goût", "Underbelly", "Mr Robot"}
+	idx := NewFuzzyIndex()
+	for _, title := range titles {
+		idx.Add(title)
+	}
+
+	// one edit of each kind at every position
+	var queries []string
+	for _, title := range titles {
+		folded := []rune(strings.Join(Tokenize(title), " "))
+		for i := range folded {
+			queries = append(queries,
+				string(folded[:i])+string(folded[i+1:]),
+				string(folded[:i])+"x"+string(folded[i+1:]),
+				string(folded[:i])+"x"+string(folded[i:]))
+			if i+1 < len(folded) {
+				swapped := slices.Clone(folded)
+				swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+				queries = append(queries, string(swapped))
+			}
+		}
+	}
+	// and every typo cut short, mid word included
+	for _, query := range slices.Clone(queries) {
+		runes := []rune(query)
+		for n := 1; n < len(runes); n++ {
+			queries = append(queries, string(runes[:n]))
+		}
+	}
+	queries = append(queries, "thnuderbirds ar", "thunderbrids are g")
+
+	for _, opts := range []FuzzyOptions{{MaxDistance: 1}, {MaxDistance: 1, Prefix: true}, {MaxDistance: 2, Prefix: true}} {
+		for _, query := range queries {
+			folded := strings.Join(Tokenize(query), " ")
+			if folded == "" {
+				continue
+			}
+			expected := []int{}
+			for doc, title := range titles {
+				if keyDistance(folded, strings.Join(Tokenize(title), " "), opts.Prefix) <= opts.MaxDistance {
+					expected = append(expected, doc)
+				}
+			}
+			matched := matchedDocs(idx.Match(query, opts))
+			slices.Sort(matched)
+			if !assert.Equal(t, expected, matched, "%q with %+v", query, opts) {
+				return
+			}
+		}
+	}
+}
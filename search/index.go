@@ -0,0 +1,209 @@
+package search
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// BM25 parameters, the usual defaults
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Field is a part of every document, matches in heavier fields rank higher
+type Field struct {
+	Name   string
+	Weight float64
+}
+
+// Index is an inverted index over documents made of the same fields.
+// It is built with Add and safe for concurrent searches afterwards.
+type Index struct {
+	fields []Field
+	// lengths holds the number of terms per document and field
+	lengths [][]int
+	// totals holds the number of terms per field over all documents
+	totals   []int
+	postings map[string][]posting
+}
+
+// posting lists where a term occurs in one field of one document
+type posting struct {
+	doc       int
+	field     int
+	positions []int
+}
+
+// Hit is a matching document, its number is the order it was added in
+type Hit struct {
+	Doc   int
+	Score float64
+}
+
+// NewIndex returns an empty index of documents with the given fields
+func NewIndex(fields ...Field) *Index {
+	return &Index{fields: fields, totals: make([]int, len(fields)), postings: map[string][]posting{}}
+}
+
+// Len returns the number of documents
+func (idx *Index) Len() int {
+	return len(idx.lengths)
+}
+
+// Add indexes a document, texts are given in the order of the fields.
+// It returns the document's number.
+func (idx *Index) Add(texts ...string) int {
+	doc := len(idx.lengths)
+	lengths := make([]int, len(idx.fields))
+	for field := range idx.fields {
+		if field >= len(texts) {
+			break
+		}
+		terms := Analyze(texts[field])
+		lengths[field] = len(terms)
+		idx.totals[field] += len(terms)
+
+		positions := map[string][]int{}
+		var order []string
+		for position, term := range terms {
+			if _, ok := positions[term]; !ok {
+				order = append(order, term)
+			}
+			positions[term] = append(positions[term], position)
+		}
+		for _, term := range order {
+			idx.postings[term] = append(idx.postings[term], posting{doc: doc, field: field, positions: positions[term]})
+		}
+	}
+	idx.lengths = append(idx.lengths, lengths)
+	return doc
+}
+
+// Search returns the documents matching every term and phrase of the
+// query, the most relevant first and ties in document order
+func (idx *Index) Search(query *Query) []Hit {
+	var candidates map[int]bool
+	narrow := func(docs map[int]bool) {
+		if candidates == nil {
+			candidates = docs
+			return
+		}
+		for doc := range candidates {
+			if !docs[doc] {
+				delete(candidates, doc)
+			}
+		}
+	}
+
+	for _, term := range query.Terms {
+		docs := map[int]bool{}
+		for _, p := range idx.postings[term] {
+			docs[p.doc] = true
+		}
+		narrow(docs)
+	}
+	phraseFields := make([]map[int][]int, len(query.Phrases))
+	for i, phrase := range query.Phrases {
+		phraseFields[i] = idx.phraseMatches(phrase)
+		docs := map[int]bool{}
+		for doc := range phraseFields[i] {
+			docs[doc] = true
+		}
+		narrow(docs)
+	}
+	if len(candidates) == 0 {
+		return []Hit{}
+	}
+
+	scores := map[int]float64{}
+	terms := slices.Clone(query.Terms)
+	for _, phrase := range query.Phrases {
+		terms = append(terms, phrase...)
+	}
+	for _, term := range terms {
+		idf := idx.idf(term)
+		for _, p := range idx.postings[term] {
+			if candidates[p.doc] {
+				scores[p.doc] += idx.fields[p.field].Weight * idf * idx.saturation(p)
+			}
+		}
+	}
+	// a phrase counts once more for every field it was found in
+	for i, phrase := range query.Phrases {
+		var idf float64
+		for _, term := range phrase {
+			idf += idx.idf(term)
+		}
+		for doc, fields := range phraseFields[i] {
+			for _, field := range fields {
+				scores[doc] += idx.fields[field].Weight * idf
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for doc := range candidates {
+		hits = append(hits, Hit{Doc: doc, Score: scores[doc]})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Doc, b.Doc)
+	})
+	return hits
+}
+
+// phraseMatches maps the documents containing the terms next to each
+// other to the fields they were found in
+func (idx *Index) phraseMatches(phrase []string) map[int][]int {
+	type location struct{ doc, field int }
+	rest := make([]map[location][]int, len(phrase))
+	for i, term := range phrase[1:] {
+		rest[i+1] = map[location][]int{}
+		for _, p := range idx.postings[term] {
+			rest[i+1][location{p.doc, p.field}] = p.positions
+		}
+	}
+
+	matches := map[int][]int{}
+	for _, p := range idx.postings[phrase[0]] {
+		at := location{p.doc, p.field}
+		for _, start := range p.positions {
+			found := true
+			for i := 1; i < len(phrase) && found; i++ {
+				_, found = slices.BinarySearch(rest[i][at], start+i)
+			}
+			if found {
+				matches[p.doc] = append(matches[p.doc], p.field)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// idf weighs a term by how rare it is
+func (idx *Index) idf(term string) float64 {
+	df := map[int]bool{}
+	for _, p := range idx.postings[term] {
+		df[p.doc] = true
+	}
+	n := float64(idx.Len())
+	return math.Log(1 + (n-float64(len(df))+0.5)/(float64(len(df))+0.5))
+}
+
+// saturation is the BM25 term frequency component, normalised by the
+// length of the field
+func (idx *Index) saturation(p posting) float64 {
+	tf := float64(len(p.positions))
+	average := float64(idx.totals[p.field]) / float64(idx.Len())
+	length := float64(idx.lengths[p.doc][p.field])
+	norm := 1.0
+	if average > 0 {
+		norm = 1 - b + b*length/average
+	}
+	return tf * (k1 + 1) / (tf + k1*norm)
+}
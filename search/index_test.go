@@ -0,0 +1,66 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testIndex() *Index {
+	idx := NewIndex(Field{Name: "title", Weight: 2}, Field{Name: "description", Weight: 1})
+	idx.Add("Le Goût", "A culinary tour of France")
+	idx.Add("Team Talk", "Inside the dressing room of a football team")
+	idx.Add("Kitchen Nightmares", "Cooking gone wrong, a team of chefs and a culinary rescue")
+	idx.Add("Football", "Highlights from every team in the league")
+	idx.Add("Untitled", "")
+	return idx
+}
+
+func docs(hits []Hit) []int {
+	out := []int{}
+	for _, hit := range hits {
+		out = append(out, hit.Doc)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []int
+	}{
+		{"Diacritics are ignored", "gout", []int{0}},
+		{"Stems match", "cooked", []int{2}},
+		{"Every term must match", "football team", []int{3, 1}},
+		{"Phrase", `"football team"`, []int{1}},
+		{"Phrase words must be adjacent", `"team football"`, []int{}},
+		{"Title matches rank higher", "team", []int{1, 3, 2}},
+		{"Case is ignored", "CULINARY", []int{0, 2}},
+		{"No match", "weather", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, docs(idx.Search(query)))
+		})
+	}
+}
+
+func TestSearchScores(t *testing.T) {
+	hits := testIndex().Search(&Query{Terms: []string{"team"}})
+	for i := 1; i < len(hits); i++ {
+		assert.GreaterOrEqual(t, hits[i-1].Score, hits[i].Score)
+	}
+	assert.Greater(t, hits[0].Score, 0.0)
+}
+
+func TestSearchEmptyIndex(t *testing.T) {
+	idx := NewIndex(Field{Name: "title", Weight: 1})
+	assert.Equal(t, 0, idx.Len())
+	assert.Empty(t, idx.Search(&Query{Terms: []string{"team"}}))
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Query is a parsed search. Every term and every phrase must match.
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+// ParseQuery reads words and "quoted phrases" from a search string,
+// analysing them the same way as indexed text
+func ParseQuery(text string) (*Query, error) {
+	if strings.Count(text, `"`)%2 != 0 {
+		return nil, errors.New("has an unterminated phrase")
+	}
+
+	query := &Query{}
+	for i, part := range strings.Split(text, `"`) {
+		terms := Analyze(part)
+		if i%2 == 0 {
+			for _, term := range terms {
+				if !slices.Contains(query.Terms, term) {
+					query.Terms = append(query.Terms, term)
+				}
+			}
+			continue
+		}
+		switch len(terms) {
+		case 0:
+		case 1:
+			// a single quoted word is just a term
+			if !slices.Contains(query.Terms, terms[0]) {
+				query.Terms = append(query.Terms, terms[0])
+			}
+		default:
+			query.Phrases = append(query.Phrases, terms)
+		}
+	}
+
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 {
+		return nil, errors.New("must contain a word")
+	}
+	return query, nil
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		expected      *Query
+		expectedError string
+	}{
+		{
+			name:     "Terms",
+			text:     "Culinary  shows",
+			expected: &Query{Terms: []string{"culinari", "show"}},
+		},
+		{
+			name:     "Repeated terms",
+			text:     "cook cooking",
+			expected: &Query{Terms: []string{"cook"}},
+		},
+		{
+			name:     "Phrase",
+			text:     `"football team" australia`,
+			expected: &Query{Terms: []string{"australia"}, Phrases: [][]string{{"footbal", "team"}}},
+		},
+		{
+			name:     "Quoted word",
+			text:     `"gout"`,
+			expected: &Query{Terms: []string{"gout"}},
+		},
+		{
+			name:          "Unterminated phrase",
+			text:          `"football team`,
+			expectedError: "has an unterminated phrase",
+		},
+		{
+			name:          "No words",
+			text:          `"" !!`,
+			expectedError: "must contain a word",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseQuery(tt.text)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package search
+
+// Stem reduces an English word to its stem with the Porter algorithm,
+// words which aren't plain lower case ASCII are returned as they are
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := []byte(word)
+	w = step1a(w)
+	w = step1b(w)
+	w = step1c(w)
+	w = step2(w)
+	w = step3(w)
+	w = step4(w)
+	w = step5(w)
+	return string(w)
+}
+
+// isConsonant reports whether w[i] is a consonant, y is one unless it
+// follows a consonant
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel consonant sequences in a stem
+func measure(stem []byte) int {
+	n, i := 0, 0
+	for i < len(stem) && isConsonant(stem, i) {
+		i++
+	}
+	for i < len(stem) {
+		for i < len(stem) && !isConsonant(stem, i) {
+			i++
+		}
+		if i == len(stem) {
+			break
+		}
+		for i < len(stem) && isConsonant(stem, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func hasVowel(stem []byte) bool {
+	for i := range stem {
+		if !isConsonant(stem, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant, vowel, consonant with the
+// last one not w, x or y, like "hop"
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	last := w[n-1]
+	return last != 'w' && last != 'x' && last != 'y'
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return len(w) >= len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+// rule replaces a suffix when the stem left before it satisfies a condition
+type rule struct {
+	suffix, replacement string
+}
+
+// longestRule finds the rule with the longest suffix w ends with, or -1
+func longestRule(w []byte, rules []rule) int {
+	best := -1
+	for i, r := range rules {
+		if hasSuffix(w, r.suffix) && (best < 0 || len(r.suffix) > len(rules[best].suffix)) {
+			best = i
+		}
+	}
+	return best
+}
+
+// applyRules replaces the longest matching suffix when the stem before it
+// passes the condition, shorter suffixes aren't tried after that
+func applyRules(w []byte, rules []rule, condition func(stem []byte) bool) []byte {
+	best := longestRule(w, rules)
+	if best < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(rules[best].suffix)]
+	if !condition(stem) {
+		return w
+	}
+	return append(stem, rules[best].replacement...)
+}
+
+func step1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"), hasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func step1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case endsDoubleConsonant(stem):
+		switch stem[len(stem)-1] {
+		case 'l', 's', 'z':
+			return stem
+		}
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+func step1c(w []byte) []byte {
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w[len(w)-1] = 'i'
+	}
+	return w
+}
+
+var step2Rules = []rule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3Rules = []rule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var step4Rules = []rule{
+	{"al", ""}, {"ance", ""}, {"ence", ""}, {"er", ""}, {"ic", ""},
+	{"able", ""}, {"ible", ""}, {"ant", ""}, {"ement", ""}, {"ment", ""},
+	{"ent", ""}, {"ion", ""}, {"ou", ""}, {"ism", ""}, {"ate", ""},
+	{"iti", ""}, {"ous", ""}, {"ive", ""}, {"ize", ""},
+}
+
+func positiveMeasure(stem []byte) bool {
+	return measure(stem) > 0
+}
+
+func step2(w []byte) []byte {
+	return applyRules(w, step2Rules, positiveMeasure)
+}
+
+func step3(w []byte) []byte {
+	return applyRules(w, step3Rules, positiveMeasure)
+}
+
+func step4(w []byte) []byte {
+	best := longestRule(w, step4Rules)
+	if best < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(step4Rules[best].suffix)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if step4Rules[best].suffix == "ion" && !hasSuffix(stem, "s") && !hasSuffix(stem, "t") {
+		return w
+	}
+	return stem
+}
+
+func step5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && hasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,194 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"stan.com/stantest/config"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Build creates the server TLS config. Certificates and the client CA are
+// served through the returned Reloader so they can change on disk without
+// a restart, connections already established keep their handshake.
+func Build(cfg config.TLS) (*tls.Config, *Reloader, error) {
+	minVersion, ok := tlsVersions[cfg.MinVersion]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported TLS min version %q", cfg.MinVersion)
+	}
+	cipherSuites, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reloader := &Reloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile, clientCAFile: cfg.ClientCAFile}
+	if err := reloader.Reload(); err != nil {
+		return nil, nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+		// HTTP/2 first, plain HTTP/1.1 for older clients
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if cfg.ClientCAFile != "" {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+		// hand out a fresh config per handshake so a reloaded CA pool is picked up
+		base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			perConn := base.Clone()
+			perConn.GetConfigForClient = nil
+			perConn.ClientCAs = reloader.ClientCAs()
+			return perConn, nil
+		}
+		base.ClientCAs = reloader.ClientCAs()
+	}
+	return base, reloader, nil
+}
+
+// parseCipherSuites maps IANA suite names to ids, insecure suites are refused
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Reloader holds the current certificate and client CA pool and swaps
+// them when the files on disk change
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// GetCertificate is the tls.Config hook returning the current certificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ClientCAs returns the current client CA pool, nil without mutual TLS
+func (r *Reloader) ClientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clientCAs
+}
+
+// Reload loads the files from disk. On error the previous material is kept.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.clientCAFile)
+		}
+	}
+
+	modTimes := r.currentModTimes()
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch polls the files every interval and reloads when any of them changed,
+// it returns when ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, logger echo.Logger) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				logger.Errorf("failed to reload TLS certificates, keeping current ones: %s", err.Error())
+				continue
+			}
+			logger.Info("TLS certificates reloaded")
+		}
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) currentModTimes() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, file := range r.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func (r *Reloader) changed() bool {
+	current := r.currentModTimes()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		if !current[file].Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
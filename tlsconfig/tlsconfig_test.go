@@ -0,0 +1,140 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"stan.com/stantest/config"
+)
+
+// writeCert writes a self signed cert/key pair with the given serial
+func writeCert(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func serialOf(t *testing.T, cert *tls.Certificate) int64 {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	return leaf.SerialNumber.Int64()
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, 1)
+
+	tests := []struct {
+		name       string
+		cfg        config.TLS
+		wantErr    bool
+		clientAuth tls.ClientAuthType
+	}{
+		{
+			name:       "Server certificate only",
+			cfg:        config.TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"},
+			clientAuth: tls.NoClientCert,
+		},
+		{
+			name:       "Mutual TLS",
+			cfg:        config.TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, MinVersion: "1.3"},
+			clientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name: "Explicit cipher suites",
+			cfg: config.TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2",
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}},
+			clientAuth: tls.NoClientCert,
+		},
+		{
+			name:    "Unknown min version",
+			cfg:     config.TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"},
+			wantErr: true,
+		},
+		{
+			name:    "Insecure cipher suite",
+			cfg:     config.TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2", CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing key file",
+			cfg:     config.TLS{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key"), MinVersion: "1.2"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, _, err := Build(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.clientAuth, tlsConfig.ClientAuth)
+			assert.Contains(t, tlsConfig.NextProtos, "h2")
+		})
+	}
+}
+
+func TestReloaderPicksUpNewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, 1)
+
+	tlsConfig, reloader, err := Build(config.TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"})
+	assert.NoError(t, err)
+
+	cert, err := tlsConfig.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), serialOf(t, cert))
+	assert.False(t, reloader.changed())
+
+	// renew on disk, mod times are pushed forward to be robust on coarse filesystems
+	writeCert(t, certFile, keyFile, 2)
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	assert.True(t, reloader.changed())
+	assert.NoError(t, reloader.Reload())
+
+	cert, err = tlsConfig.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), serialOf(t, cert))
+
+	// a broken renewal keeps serving the last good certificate
+	assert.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	assert.Error(t, reloader.Reload())
+	cert, err = tlsConfig.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), serialOf(t, cert))
+}
@@ -0,0 +1,221 @@
+package urlpolicy
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Violation names a rule a URL broke
+type Violation string
+
+const (
+	// Invalid is not an absolute http(s) URL with a host
+	Invalid Violation = "invalid"
+	// Credentials is a username or password in the URL
+	Credentials Violation = "credentials"
+	// HTTPSRequired is a plain http URL when only https is allowed
+	HTTPSRequired Violation = "https-required"
+	// HostNotAllowed is a host missing from the allow list
+	HostNotAllowed Violation = "host-not-allowed"
+	// HostDenied is a host on the deny list
+	HostDenied Violation = "host-denied"
+	// IPLiteral is an IP address used as host
+	IPLiteral Violation = "ip-literal"
+	// PrivateAddress is a loopback, private or link local host
+	PrivateAddress Violation = "private-address"
+	// TooLong is a URL over the maximum length
+	TooLong Violation = "too-long"
+	// Extension is an image path without an allowed file extension
+	Extension Violation = "extension"
+)
+
+// Policy decides which URLs the feed may carry. The zero value only asks
+// for a well formed http(s) URL without credentials.
+type Policy struct {
+	// AllowHosts limits URLs to these hosts, "*.example.com" matches subdomains.
+	// Empty allows every host.
+	AllowHosts []string
+	// DenyHosts are refused, same matching as AllowHosts
+	DenyHosts []string
+	// HTTPSOnly refuses plain http
+	HTTPSOnly bool
+	// UpgradeHTTP rewrites http to https before the other rules are checked
+	UpgradeHTTP bool
+	// RejectIPLiterals refuses hosts given as an IP address
+	RejectIPLiterals bool
+	// RejectPrivate refuses loopback, private and link local hosts
+	RejectPrivate bool
+	// MaxLength caps the length of the URL, 0 disables
+	MaxLength int
+	// ImageExtensions are required at the end of image paths, e.g. ".jpg".
+	// Empty allows any path.
+	ImageExtensions []string
+}
+
+// Result is the outcome of checking one URL
+type Result struct {
+	// URL is the checked URL, rewritten to https when the policy upgrades it
+	URL string
+	// Rewritten is set when URL differs from the input
+	Rewritten bool
+	// Violations lists every rule broken, empty means the URL is allowed
+	Violations []Violation
+}
+
+// OK reports whether the URL passed the policy
+func (r Result) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Check applies the policy to a link
+func (p *Policy) Check(raw string) Result {
+	return p.check(raw, false)
+}
+
+// CheckImage applies the policy to an image URL, which also has to
+// carry one of the image extensions
+func (p *Policy) CheckImage(raw string) Result {
+	return p.check(raw, true)
+}
+
+func (p *Policy) check(raw string, image bool) Result {
+	result := Result{URL: raw}
+
+	// one parse, the host and scheme checks refuse relative references
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		result.Violations = []Violation{Invalid}
+		return result
+	}
+
+	if u.Scheme == "http" && p.UpgradeHTTP {
+		u.Scheme = "https"
+		result.URL = u.String()
+		result.Rewritten = true
+	}
+
+	if u.User != nil {
+		result.Violations = append(result.Violations, Credentials)
+	}
+	if u.Scheme == "http" && p.HTTPSOnly {
+		result.Violations = append(result.Violations, HTTPSRequired)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if len(p.AllowHosts) > 0 && !matchesAny(host, p.AllowHosts) {
+		result.Violations = append(result.Violations, HostNotAllowed)
+	}
+	if matchesAny(host, p.DenyHosts) {
+		result.Violations = append(result.Violations, HostDenied)
+	}
+
+	addr, isIP := parseIP(host)
+	if isIP && p.RejectIPLiterals {
+		result.Violations = append(result.Violations, IPLiteral)
+	}
+	if p.RejectPrivate && isPrivate(host, addr, isIP) {
+		result.Violations = append(result.Violations, PrivateAddress)
+	}
+
+	if p.MaxLength > 0 && len(result.URL) > p.MaxLength {
+		result.Violations = append(result.Violations, TooLong)
+	}
+	if image && len(p.ImageExtensions) > 0 {
+		ext := strings.ToLower(path.Ext(u.Path))
+		if !slices.ContainsFunc(p.ImageExtensions, func(allowed string) bool {
+			return strings.EqualFold(ext, allowed)
+		}) {
+			result.Violations = append(result.Violations, Extension)
+		}
+	}
+
+	return result
+}
+
+// matchesAny reports whether host is one of the patterns, a "*." pattern
+// matches any subdomain but not the domain itself
+func matchesAny(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP recognises IP literals, including the decimal and hex
+// shorthands browsers accept for IPv4
+func parseIP(host string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap(), true
+	}
+	if ip := parseLegacyIPv4(host); ip != nil {
+		addr, _ := netip.AddrFromSlice(ip.To4())
+		return addr, true
+	}
+	return netip.Addr{}, false
+}
+
+// parseLegacyIPv4 handles hosts like 2130706433 or 0x7f000001 which
+// resolve to 127.0.0.1 in most clients
+func parseLegacyIPv4(host string) net.IP {
+	if host == "" || strings.Contains(host, ".") {
+		return nil
+	}
+	var value uint64
+	digits := host
+	base := uint64(10)
+	if rest, ok := strings.CutPrefix(digits, "0x"); ok {
+		digits, base = rest, 16
+	}
+	if digits == "" {
+		return nil
+	}
+	for _, r := range digits {
+		var d uint64
+		switch {
+		case r >= '0' && r <= '9':
+			d = uint64(r - '0')
+		case base == 16 && r >= 'a' && r <= 'f':
+			d = uint64(r-'a') + 10
+		default:
+			return nil
+		}
+		value = value*base + d
+		if value > 0xffffffff {
+			return nil
+		}
+	}
+	return net.IPv4(byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
+
+// privateNames are host names which never point at the public internet
+var privateNames = []string{"localhost", "*.localhost", "*.local", "*.internal", "*.localdomain"}
+
+// isPrivate reports hosts in loopback, private, link local or unspecified
+// ranges, host names are not resolved
+func isPrivate(host string, addr netip.Addr, isIP bool) bool {
+	if !isIP {
+		return matchesAny(host, privateNames)
+	}
+	return IsPrivateIP(addr)
+}
+
+// IsPrivateIP reports addresses in loopback, private, link local,
+// multicast or unspecified ranges
+func IsPrivateIP(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() || addr.IsMulticast()
+}
@@ -0,0 +1,83 @@
+package vocab
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// countryAliases are spellings seen in feeds which CLDR doesn't know
+var countryAliases = map[string]string{
+	"uk":                       "GB",
+	"great britain":            "GB",
+	"britain":                  "GB",
+	"england":                  "GB",
+	"scotland":                 "GB",
+	"wales":                    "GB",
+	"northern ireland":         "GB",
+	"usa":                      "US",
+	"america":                  "US",
+	"united states of america": "US",
+	"holland":                  "NL",
+	"korea":                    "KR",
+	"republic of korea":        "KR",
+	"russian federation":       "RU",
+}
+
+// countryNames maps lower case English country names to ISO 3166-1 alpha-2 codes
+var countryNames = buildCountryNames()
+
+func buildCountryNames() map[string]string {
+	names := map[string]string{}
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b++ {
+			code := string([]rune{a, b})
+			region, ok := country(code)
+			if !ok || region.String() != code {
+				continue
+			}
+			if name := display.English.Regions().Name(region); name != "" {
+				names[strings.ToLower(name)] = code
+			}
+		}
+	}
+	return names
+}
+
+// country parses an ISO 3166-1 alpha-2 or alpha-3 code into a canonical country
+func country(code string) (language.Region, bool) {
+	region, err := language.ParseRegion(code)
+	if err != nil {
+		return region, false
+	}
+	region = region.Canonicalize()
+	return region, region.IsCountry() && region.ISO3() != ""
+}
+
+// Country normalises a country code, name or alias to its ISO 3166-1 alpha-2 code
+func Country(s string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	if key == "" {
+		return "", false
+	}
+	if code, ok := countryAliases[key]; ok {
+		return code, true
+	}
+	if code, ok := countryNames[key]; ok {
+		return code, true
+	}
+	if region, ok := country(key); ok {
+		return region.String(), true
+	}
+	return "", false
+}
+
+// CountryName is the English name of an ISO 3166-1 alpha-2 code
+func CountryName(code string) string {
+	region, ok := country(code)
+	if !ok {
+		return ""
+	}
+	return display.English.Regions().Name(region)
+}
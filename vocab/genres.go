@@ -0,0 +1,97 @@
+package vocab
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Genre is one entry of the genre taxonomy
+type Genre struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// Taxonomy is the set of genres we recognise
+type Taxonomy struct {
+	Genres []Genre `json:"genres"`
+	// lookup maps lower case codes, names and aliases to codes
+	lookup map[string]string
+}
+
+// Genres is the taxonomy in use, replaced at startup when a taxonomy file is configured
+var Genres = DefaultTaxonomy()
+
+// DefaultTaxonomy is the built in genre taxonomy
+func DefaultTaxonomy() *Taxonomy {
+	t, _ := NewTaxonomy([]Genre{
+		{Code: "action", Name: "Action"},
+		{Code: "adventure", Name: "Adventure"},
+		{Code: "animation", Name: "Animation", Aliases: []string{"animated", "cartoon"}},
+		{Code: "comedy", Name: "Comedy", Aliases: []string{"sitcom"}},
+		{Code: "crime", Name: "Crime"},
+		{Code: "documentary", Name: "Documentary", Aliases: []string{"doco", "factual"}},
+		{Code: "drama", Name: "Drama"},
+		{Code: "family", Name: "Family"},
+		{Code: "fantasy", Name: "Fantasy"},
+		{Code: "game-show", Name: "Game Show", Aliases: []string{"gameshow", "quiz"}},
+		{Code: "horror", Name: "Horror"},
+		{Code: "kids", Name: "Kids", Aliases: []string{"children", "childrens", "children's"}},
+		{Code: "lifestyle", Name: "Lifestyle"},
+		{Code: "music", Name: "Music"},
+		{Code: "mystery", Name: "Mystery"},
+		{Code: "news", Name: "News", Aliases: []string{"current affairs"}},
+		{Code: "reality", Name: "Reality", Aliases: []string{"reality tv"}},
+		{Code: "romance", Name: "Romance"},
+		{Code: "sci-fi", Name: "Sci-Fi", Aliases: []string{"science fiction", "scifi"}},
+		{Code: "sport", Name: "Sport", Aliases: []string{"sports"}},
+		{Code: "talk-show", Name: "Talk Show", Aliases: []string{"talk"}},
+		{Code: "thriller", Name: "Thriller"},
+		{Code: "war", Name: "War"},
+		{Code: "western", Name: "Western"},
+	})
+	return t
+}
+
+// NewTaxonomy indexes genres, codes, names and aliases must be unique
+func NewTaxonomy(genres []Genre) (*Taxonomy, error) {
+	t := &Taxonomy{Genres: genres, lookup: map[string]string{}}
+	for _, genre := range genres {
+		if genre.Code == "" {
+			return nil, fmt.Errorf("genre %q has no code", genre.Name)
+		}
+		for _, key := range append([]string{genre.Code, genre.Name}, genre.Aliases...) {
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" {
+				continue
+			}
+			if code, ok := t.lookup[key]; ok && code != genre.Code {
+				return nil, fmt.Errorf("genre alias %q is used by both %s and %s", key, code, genre.Code)
+			}
+			t.lookup[key] = genre.Code
+		}
+	}
+	return t, nil
+}
+
+// LoadTaxonomy reads a taxonomy from a JSON file of the form
+// {"genres": [{"code": "reality", "name": "Reality", "aliases": ["reality tv"]}]}
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file Taxonomy
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse genre taxonomy %s: %w", path, err)
+	}
+	return NewTaxonomy(file.Genres)
+}
+
+// Normalize maps a genre code, name or alias to its code
+func (t *Taxonomy) Normalize(s string) (string, bool) {
+	code, ok := t.lookup[strings.ToLower(strings.TrimSpace(s))]
+	return code, ok
+}
@@ -0,0 +1,51 @@
+package vocab
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// languageNames maps lower case English language names to ISO 639-1 codes
+var languageNames = buildLanguageNames()
+
+func buildLanguageNames() map[string]string {
+	names := map[string]string{}
+	for a := 'a'; a <= 'z'; a++ {
+		for b := 'a'; b <= 'z'; b++ {
+			code := string([]rune{a, b})
+			base, err := language.ParseBase(code)
+			if err != nil || base.String() != code {
+				continue
+			}
+			if name := display.English.Languages().Name(base); name != "" {
+				names[strings.ToLower(name)] = code
+			}
+		}
+	}
+	return names
+}
+
+// Language normalises a BCP 47 tag or English language name to a canonical
+// BCP 47 tag, e.g. "English" to "en" and "EN-au" to "en-AU"
+func Language(s string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	if key == "" {
+		return "", false
+	}
+	if code, ok := languageNames[key]; ok {
+		return code, true
+	}
+	tag, err := language.Parse(key)
+	if err != nil || tag == language.Und {
+		return "", false
+	}
+	return tag.String(), true
+}
+
+// LanguageMatches reports whether tag falls under want, a bare language
+// like "en" matches all its regional variants
+func LanguageMatches(tag, want string) bool {
+	return tag == want || strings.HasPrefix(tag, want+"-")
+}
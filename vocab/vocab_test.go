@@ -0,0 +1,103 @@
+package vocab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantOK   bool
+	}{
+		{input: "UK", expected: "GB", wantOK: true},
+		{input: "United Kingdom", expected: "GB", wantOK: true},
+		{input: "gb", expected: "GB", wantOK: true},
+		{input: "GBR", expected: "GB", wantOK: true},
+		{input: " USA", expected: "US", wantOK: true},
+		{input: "United States", expected: "US", wantOK: true},
+		{input: "australia", expected: "AU", wantOK: true},
+		{input: "Narnia", wantOK: false},
+		{input: "EU", wantOK: false},
+		{input: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			code, ok := Country(tt.input)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+	assert.Equal(t, "United Kingdom", CountryName("GB"))
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantOK   bool
+	}{
+		{input: "English", expected: "en", wantOK: true},
+		{input: "en", expected: "en", wantOK: true},
+		{input: "eng", expected: "en", wantOK: true},
+		{input: "EN-au", expected: "en-AU", wantOK: true},
+		{input: "french", expected: "fr", wantOK: true},
+		{input: "zh-Hant-TW", expected: "zh-Hant-TW", wantOK: true},
+		{input: "Klingonese", wantOK: false},
+		{input: "xx", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tag, ok := Language(tt.input)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+
+	assert.True(t, LanguageMatches("en-AU", "en"))
+	assert.True(t, LanguageMatches("en", "en"))
+	assert.False(t, LanguageMatches("en", "en-AU"))
+	assert.False(t, LanguageMatches("eo", "e"))
+}
+
+func TestGenres(t *testing.T) {
+	code, ok := DefaultTaxonomy().Normalize("Reality TV")
+	assert.True(t, ok)
+	assert.Equal(t, "reality", code)
+
+	code, ok = DefaultTaxonomy().Normalize("Science Fiction")
+	assert.True(t, ok)
+	assert.Equal(t, "sci-fi", code)
+
+	_, ok = DefaultTaxonomy().Normalize("Cooking")
+	assert.False(t, ok)
+}
+
+func TestLoadTaxonomy(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "genres.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"genres": [
+		{"code": "food", "name": "Food", "aliases": ["cooking", "culinary"]},
+		{"code": "reality", "name": "Reality"}
+	]}`), 0644))
+	taxonomy, err := LoadTaxonomy(path)
+	assert.NoError(t, err)
+	code, ok := taxonomy.Normalize("Culinary")
+	assert.True(t, ok)
+	assert.Equal(t, "food", code)
+
+	clash := filepath.Join(dir, "clash.json")
+	assert.NoError(t, os.WriteFile(clash, []byte(`{"genres": [
+		{"code": "kids", "aliases": ["family"]},
+		{"code": "family"}
+	]}`), 0644))
+	_, err = LoadTaxonomy(clash)
+	assert.Error(t, err)
+}